@@ -57,6 +57,113 @@ type DatabaseSpec struct {
 
 	// Defines the Name of the app to share a database from
 	SharedDBAppName string `json:"sharedDbAppName,omitempty"`
+
+	// A list of PostgreSQL extensions required by the app, e.g. pg_trgm. In
+	// (*_local_*) mode these will be created in the database after startup, in
+	// (*_app-interface_*) mode their presence will be validated and any missing
+	// extensions reported in the ClowdApp status.
+	Extensions []string `json:"extensions,omitempty"`
+
+	// A map of PostgreSQL server parameters, e.g. max_connections, to be
+	// applied to the database server in (*_local_*) mode.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// BucketDeletionPolicy defines what happens to a bucket once no app requests it, one of 'retain'
+// or 'delete'
+// +kubebuilder:validation:Enum:=retain;delete
+type BucketDeletionPolicy string
+
+// ObjectStoreBucketSpec defines a storage bucket and the settings applied to it.
+type ObjectStoreBucketSpec struct {
+	// The requested name of the bucket.
+	// +kubebuilder:validation:MinLength:=3
+	Name string `json:"name"`
+
+	// The number of days after which objects in the bucket are deleted. If unset,
+	// objects do not expire.
+	// +kubebuilder:validation:Minimum:=1
+	ExpirationDays *int32 `json:"expirationDays,omitempty"`
+
+	// Enables versioning of the objects in the bucket.
+	Versioning bool `json:"versioning,omitempty"`
+
+	// Allows anonymous read access to the objects in the bucket.
+	PublicRead bool `json:"publicRead,omitempty"`
+
+	// What happens to the bucket once no app in the environment requests it,
+	// either because the app was removed or stopped requesting the bucket.
+	// 'retain', the default, keeps the bucket and its objects, 'delete' deletes
+	// them. A bucket requested by several apps is only deleted if they all set
+	// 'delete'.
+	DeletionPolicy BucketDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Objects to upload to the bucket when it is first created, intended for
+	// test environments. Only used in (*_minio_*) mode.
+	Fixtures *BucketFixtures `json:"fixtures,omitempty"`
+}
+
+// BucketFixtures defines where the objects used to pre-populate a bucket are found. Exactly one of
+// configMap or image must be set.
+type BucketFixtures struct {
+	// The name of a ConfigMap in the app's namespace. Each key is uploaded as
+	// an object of the same name.
+	ConfigMap string `json:"configMap,omitempty"`
+
+	// An image containing the objects to upload. The image must provide sh,
+	// cp and tar.
+	Image string `json:"image,omitempty"`
+
+	// The path in the image of the objects to upload, either a directory whose
+	// contents are uploaded or a tarball which is extracted first.
+	Path string `json:"path,omitempty"`
+}
+
+// SharedBucketSpec references a storage bucket requested by another ClowdApp in the same
+// environment.
+type SharedBucketSpec struct {
+	// The name of the ClowdApp requesting the bucket. The app must also be
+	// listed in dependencies.
+	App string `json:"app"`
+
+	// The requested name of the bucket in the other ClowdApp.
+	Bucket string `json:"bucket"`
+
+	// Limits access to reading the bucket's objects. Only supported in
+	// (*_minio_*) mode, where each app has its own credentials.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// InMemoryDBMode defines whether an app is given an in-memory database of its own, one of
+// 'dedicated' or 'shared'
+// +kubebuilder:validation:Enum:=dedicated;shared
+type InMemoryDBMode string
+
+// InMemoryDBSpec defines the options of the in-memory database requested by a ClowdApp.
+type InMemoryDBSpec struct {
+	// In (*_redis_*) mode, 'dedicated', the default, creates an instance for
+	// the app alone, while 'shared' creates a user for the app on a single
+	// instance shared by the apps in the environment. Shared users may not run
+	// commands, such as FLUSHALL, which affect other apps.
+	Mode InMemoryDBMode `json:"mode,omitempty"`
+
+	// In (*_elasticache_*) mode, the name of the secret in the app's namespace
+	// holding the connection details of the Elasticache instance, defaults to
+	// 'in-memory-db'.
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// AppLoggingSpec defines the level and format a ClowdApp should log with.
+type AppLoggingSpec struct {
+	LogSettings `json:",inline"`
+
+	// If set to true, the app reloads its log settings when the configuration
+	// changes, so its pods are not restarted when only the log settings change.
+	HotReload bool `json:"hotReload,omitempty"`
+
+	// In (*_kafka_*) logging mode, setting this to true stops the app being
+	// given the logging topic, and it is given no logging configuration.
+	DisableKafka bool `json:"disableKafka,omitempty"`
 }
 
 // Job defines a CronJob as Schedule is required. In the future omitting the
@@ -144,6 +251,10 @@ type Deployment struct {
 
 	// K8sAccessLevel defines the level of access for this deployment
 	K8sAccessLevel K8sAccessLevel `json:"k8sAccessLevel,omitempty"`
+
+	// The level and format this deployment should log with, overriding those
+	// of the ClowdApp and the ClowdEnvironment.
+	Logging *LogSettings `json:"logging,omitempty"`
 }
 
 // PodSpec defines a container running inside a ClowdApp.
@@ -206,7 +317,9 @@ type PodSpecDeprecated struct {
 }
 
 // CyndiSpec is used to indicate whether a ClowdApp needs database syndication configured by the
-// cyndi operator and exposes a limited set of cyndi configuration options
+// cyndi operator and exposes a limited set of cyndi configuration options. The name of the table
+// hosts are syndicated into cannot be set, since the CyndiPipeline has no such option; the cyndi
+// operator creates versioned tables itself and exposes the active one as the inventory.hosts view.
 type CyndiSpec struct {
 	Enabled bool `json:"enabled,omitempty"`
 
@@ -216,6 +329,49 @@ type CyndiSpec struct {
 	AppName string `json:"appName,omitempty"`
 
 	InsightsOnly bool `json:"insightsOnly,omitempty"`
+
+	// Additional filters restricting the hosts syndicated to the app, passed through to the
+	// CyndiPipeline unchanged.
+	AdditionalFilters []map[string]string `json:"additionalFilters,omitempty"`
+
+	// The percentage of hosts that may differ between host-inventory and the app's table before
+	// the pipeline is considered invalid and refreshed.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=100
+	ValidationThreshold *int64 `json:"validationThreshold,omitempty"`
+
+	// The maximum age, in days, of hosts considered when validating the pipeline.
+	// +kubebuilder:validation:Minimum:=0
+	MaxAge *int64 `json:"maxAge,omitempty"`
+
+	// The host-inventory events topic to syndicate hosts from, if not the cyndi operator's
+	// default.
+	// +kubebuilder:validation:MinLength:=1
+	Topic string `json:"topic,omitempty"`
+}
+
+// KafkaTopicAccess defines the access an app requires to a topic, one of 'produce', 'consume' or
+// 'both'
+// +kubebuilder:validation:Enum={"produce", "consume", "both", ""}
+type KafkaTopicAccess string
+
+const (
+	// KafkaTopicAccessProduce allows the app to only write to a topic
+	KafkaTopicAccessProduce KafkaTopicAccess = "produce"
+	// KafkaTopicAccessConsume allows the app to only read from a topic
+	KafkaTopicAccessConsume KafkaTopicAccess = "consume"
+	// KafkaTopicAccessBoth allows the app to read from and write to a topic
+	KafkaTopicAccessBoth KafkaTopicAccess = "both"
+)
+
+// CanProduce returns true if the topic access allows the app to write to the topic.
+func (a KafkaTopicAccess) CanProduce() bool {
+	return a != KafkaTopicAccessConsume
+}
+
+// CanConsume returns true if the topic access allows the app to read from the topic.
+func (a KafkaTopicAccess) CanConsume() bool {
+	return a != KafkaTopicAccessProduce
 }
 
 // KafkaTopicSpec defines the desired state of KafkaTopic
@@ -226,10 +382,21 @@ type KafkaTopicSpec struct {
 	//   * replicas optional
 	//   * topicName required
 
+	// The access the app requires to this topic, one of 'produce', 'consume' or 'both'. In
+	// (*_operator_*) mode the app's KafkaUser is only granted the ACLs needed for this access. If
+	// unset, default is 'both'.
+	// +optional
+	Access KafkaTopicAccess `json:"access,omitempty"`
+
 	// A key/value pair describing the configuration of a particular topic.
 	// +optional
 	Config strimzi.KafkaTopicSpecConfig `json:"config,omitempty"`
 
+	// If set, a '<topicName>.dlq' dead-letter topic is created alongside this topic, with a
+	// retention of 7 days. Only used in (*_operator_*), (*_local_*) and (*_local-kraft_*) modes.
+	// +optional
+	DeadLetter bool `json:"deadLetter,omitempty"`
+
 	// The requested number of partitions for this topic. If unset, default is '3'
 	// +optional
 	// +kubebuilder:validation:Minimum:=1
@@ -242,6 +409,12 @@ type KafkaTopicSpec struct {
 	// +kubebuilder:validation:Maximum:=32767
 	Replicas int32 `json:"replicas,omitempty"`
 
+	// The delays of the retry topics to create alongside this topic. A '<topicName>.retry.N'
+	// topic is created for the Nth delay, starting at 1, with a retention of the delay plus one
+	// day. Only used in (*_operator_*), (*_local_*) and (*_local-kraft_*) modes.
+	// +optional
+	RetryTopics []metav1.Duration `json:"retryTopics,omitempty"`
+
 	// The requested name for this topic.
 	// +kubebuilder:validation:MinLength:=1
 	// +kubebuilder:validation:MaxLength:=249
@@ -249,6 +422,51 @@ type KafkaTopicSpec struct {
 	TopicName string `json:"topicName"`
 }
 
+// KafkaSchemaType details the format of a schema registered in the schema registry.
+// +kubebuilder:validation:Enum=AVRO;JSON;PROTOBUF
+type KafkaSchemaType string
+
+// KafkaSchemaSpec defines a schema to be registered in the environment's schema registry.
+type KafkaSchemaSpec struct {
+	// The subject the schema is registered under, usually '<topicName>-value'.
+	// +kubebuilder:validation:MinLength:=1
+	Subject string `json:"subject"`
+
+	// The format of the schema, one of 'AVRO', 'JSON' or 'PROTOBUF'. If unset, default is
+	// 'AVRO'.
+	// +optional
+	SchemaType KafkaSchemaType `json:"schemaType,omitempty"`
+
+	// The schema definition itself.
+	// +kubebuilder:validation:MinLength:=1
+	Schema string `json:"schema"`
+}
+
+// KafkaConnectorSpec defines a Kafka Connect connector to be run on the environment's Kafka Connect
+// cluster.
+type KafkaConnectorSpec struct {
+	// The name of the connector, the KafkaConnector resource is named '<app>-<name>'.
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:MaxLength:=63
+	// +kubebuilder:validation:Pattern:="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	Name string `json:"name"`
+
+	// The Java class of the connector, e.g. 'io.debezium.connector.postgresql.PostgresConnector'.
+	// +kubebuilder:validation:MinLength:=1
+	Class string `json:"class"`
+
+	// The maximum number of tasks for the connector. If unset, default is '1'
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	TasksMax int32 `json:"tasksMax,omitempty"`
+
+	// The connector configuration. Values may reference the app's own database using the
+	// placeholders ${db.hostname}, ${db.port}, ${db.name}, ${db.username}, ${db.password},
+	// ${db.adminUsername}, ${db.adminPassword} and ${db.sslMode}.
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+}
+
 type TestingSpec struct {
 	IqePlugin string `json:"iqePlugin"`
 }
@@ -274,19 +492,49 @@ type ClowdAppSpec struct {
 	// the pods listed in the ClowdApp.
 	KafkaTopics []KafkaTopicSpec `json:"kafkaTopics,omitempty"`
 
+	// The level and format the app should log with, overriding those of the
+	// ClowdEnvironment.
+	Logging *AppLoggingSpec `json:"logging,omitempty"`
+
+	// Quotas applied to the app's Kafka user, overriding the environment's default quotas. Quotas
+	// are only applied when the kafka provider is in (*_operator_*) mode.
+	KafkaQuotas *strimzi.KafkaUserSpecQuotas `json:"kafkaQuotas,omitempty"`
+
+	// A list of schemas to be registered in the environment's schema registry. Schemas are
+	// only registered if the schemaRegistry provider is enabled in the ClowdEnvironment.
+	KafkaSchemas []KafkaSchemaSpec `json:"kafkaSchemas,omitempty"`
+
+	// A list of Kafka Connect connectors to be run for the app. Connectors are only created when
+	// the kafka provider is in (*_operator_*) mode.
+	KafkaConnectors []KafkaConnectorSpec `json:"kafkaConnectors,omitempty"`
+
 	// The database specification defines a single database, the configuration
 	// of which will be made available to all the pods in the ClowdApp.
 	Database DatabaseSpec `json:"database,omitempty"`
 
 	// A list of string names defining storage buckets. In certain modes,
 	// defined by the ClowdEnvironment, Clowder will create those buckets.
+	// Equivalent to objectStoreBuckets entries with only a name set.
 	ObjectStore []string `json:"objectStore,omitempty"`
 
+	// A list of storage buckets, along with the settings to apply to them. In
+	// (*_minio_*) mode Clowder creates the buckets and applies the settings; in
+	// other modes only the bucket names are used.
+	ObjectStoreBuckets []ObjectStoreBucketSpec `json:"objectStoreBuckets,omitempty"`
+
+	// A list of storage buckets requested by other ClowdApps in the environment
+	// that this app needs access to. They are presented in the app's config
+	// alongside its own buckets.
+	SharedBuckets []SharedBucketSpec `json:"sharedBuckets,omitempty"`
+
 	// If inMemoryDb is set to true, Clowder will pass configuration
-	// of an In Memory Database to the pods in the ClowdApp. This single
-	// instance will be shared between all apps.
+	// of an In Memory Database to the pods in the ClowdApp.
 	InMemoryDB bool `json:"inMemoryDb,omitempty"`
 
+	// Options for the In Memory Database requested with inMemoryDb, which are
+	// ignored unless inMemoryDb is set to true.
+	InMemoryDBOptions *InMemoryDBSpec `json:"inMemoryDbOptions,omitempty"`
+
 	// If featureFlags is set to true, Clowder will pass configuration of a
 	// FeatureFlags instance to the pods in the ClowdApp. This single
 	// instance will be shared between all apps.
@@ -323,6 +571,24 @@ const (
 	ReconciliationPartiallySuccessful ClowdConditionType = "ReconciliationPartiallySuccessful"
 	// ReconciliationFailed means the reconciliation failed
 	ReconciliationFailed ClowdConditionType = "ReconciliationFailed"
+	// DatabaseExtensionsReady means all the requested database extensions are present
+	DatabaseExtensionsReady ClowdConditionType = "DatabaseExtensionsReady"
+	// KafkaTopicConfigConflicts means another app requested different settings for one of the
+	// app's topics
+	KafkaTopicConfigConflicts ClowdConditionType = "KafkaTopicConfigConflicts"
+	// KafkaConnectorsReady means all the app's Kafka Connect connectors are running
+	KafkaConnectorsReady ClowdConditionType = "KafkaConnectorsReady"
+	// CyndiPipelineValid means the app's CyndiPipeline has synced and passed validation
+	CyndiPipelineValid ClowdConditionType = "CyndiPipelineValid"
+	// ObjectStoreFixturesLoaded means the fixtures requested for the app's buckets have been
+	// uploaded
+	ObjectStoreFixturesLoaded ClowdConditionType = "ObjectStoreFixturesLoaded"
+	// KafkaSchemasRegistered means the schemas the app declares have been registered with the
+	// environment's schema registry
+	KafkaSchemasRegistered ClowdConditionType = "KafkaSchemasRegistered"
+	// KafkaLoggingDisabled means the environment uses kafka logging, but the app is given no
+	// logging configuration, as it requests no topics or has opted out
+	KafkaLoggingDisabled ClowdConditionType = "KafkaLoggingDisabled"
 )
 
 type ClowdCondition struct {
@@ -339,6 +605,66 @@ type ClowdCondition struct {
 	Message string `json:"message,omitempty"`
 }
 
+// The following function was modified from the kubnernetes repo under the apache license here
+// https://github.com/kubernetes/kubernetes/blob/v1.21.1/pkg/api/v1/pod/util.go#L317-L367
+func GetClowdAppConditionFromList(conditions []ClowdCondition, conditionType ClowdConditionType) (int, *ClowdCondition) {
+	if conditions == nil {
+		return -1, nil
+	}
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return i, &conditions[i]
+		}
+	}
+	return -1, nil
+}
+
+// The following function was modified from the kubnernetes repo under the apache license here
+// https://github.com/kubernetes/kubernetes/blob/v1.21.1/pkg/api/v1/pod/util.go#L317-L367
+func GetClowdAppCondition(status *ClowdAppStatus, conditionType ClowdConditionType) (int, *ClowdCondition) {
+	if status == nil {
+		return -1, nil
+	}
+	return GetClowdAppConditionFromList(status.Conditions, conditionType)
+}
+
+// The following function was modified from the kubnernetes repo under the apache license here
+// https://github.com/kubernetes/kubernetes/blob/v1.21.1/pkg/api/v1/pod/util.go#L317-L367
+func UpdateClowdAppCondition(status *ClowdAppStatus, condition *ClowdCondition) bool {
+	condition.LastTransitionTime = metav1.Now()
+	// Try to find this clowdapp condition.
+	conditionIndex, oldCondition := GetClowdAppCondition(status, condition.Type)
+
+	if oldCondition == nil {
+		// We are adding new pod condition.
+		status.Conditions = append(status.Conditions, *condition)
+		return true
+	}
+	// We are updating an existing condition, so we need to check if it has changed.
+	if condition.Status == oldCondition.Status {
+		condition.LastTransitionTime = oldCondition.LastTransitionTime
+	}
+
+	isEqual := condition.Status == oldCondition.Status &&
+		condition.Reason == oldCondition.Reason &&
+		condition.Message == oldCondition.Message &&
+		condition.LastTransitionTime.Equal(&oldCondition.LastTransitionTime)
+
+	status.Conditions[conditionIndex] = *condition
+	// Return true if one of the fields have changed.
+	return !isEqual
+}
+
+// RemoveClowdCondition removes the condition of the given type from the list, if present.
+func RemoveClowdCondition(conditions *[]ClowdCondition, conditionType ClowdConditionType) {
+	for i := range *conditions {
+		if (*conditions)[i].Type == conditionType {
+			*conditions = append((*conditions)[:i], (*conditions)[i+1:]...)
+			return
+		}
+	}
+}
+
 // ClowdAppStatus defines the observed state of ClowdApp
 type ClowdAppStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -468,6 +794,41 @@ func (i *ClowdApp) GetClowdSAName() string {
 	return fmt.Sprintf("%s-app", i.GetClowdName())
 }
 
+// GetObjectStoreBuckets returns the buckets requested in objectStoreBuckets, followed by those
+// requested by name only in objectStore. A bucket listed in both is only returned once, with the
+// settings from objectStoreBuckets.
+func (i *ClowdApp) GetObjectStoreBuckets() []ObjectStoreBucketSpec {
+	buckets := []ObjectStoreBucketSpec{}
+	seen := map[string]bool{}
+
+	for _, bucket := range i.Spec.ObjectStoreBuckets {
+		if seen[bucket.Name] {
+			continue
+		}
+		seen[bucket.Name] = true
+		buckets = append(buckets, bucket)
+	}
+
+	for _, name := range i.Spec.ObjectStore {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		buckets = append(buckets, ObjectStoreBucketSpec{Name: name})
+	}
+
+	return buckets
+}
+
+// GetInMemoryDBOptions returns the options given in inMemoryDbOptions, or the defaults when they
+// are not set.
+func (i *ClowdApp) GetInMemoryDBOptions() InMemoryDBSpec {
+	if i.Spec.InMemoryDBOptions == nil {
+		return InMemoryDBSpec{}
+	}
+	return *i.Spec.InMemoryDBOptions
+}
+
 // ConvertToNewShim converts an old "pod" based spec into the new "deployment" style.
 func (i *ClowdApp) ConvertToNewShim() {
 	deps := []Deployment{}
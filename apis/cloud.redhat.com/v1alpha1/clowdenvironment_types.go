@@ -86,9 +86,47 @@ type MetricsConfig struct {
 // TODO: Other potential mode: saas
 
 // KafkaMode details the mode of operation of the Clowder Kafka Provider
-// +kubebuilder:validation:Enum=managed;operator;app-interface;local;none
+// +kubebuilder:validation:Enum=managed;operator;app-interface;local;local-kraft;none
 type KafkaMode string
 
+// KafkaClusterAuthentication details how clients authenticate to the Kafka cluster
+// +kubebuilder:validation:Enum=scram-sha-512;tls
+type KafkaClusterAuthentication string
+
+// KafkaExternalListenerType details how the Kafka cluster is exposed outside of the k8s cluster
+// +kubebuilder:validation:Enum=nodeport;ingress
+type KafkaExternalListenerType string
+
+// KafkaExternalListenerConfig defines a listener exposing the Kafka cluster outside of the k8s
+// cluster
+type KafkaExternalListenerConfig struct {
+	// The type of the listener. Valid options are (*_nodeport_*) which exposes each broker on a
+	// NodePort, and (*_ingress_*) which exposes each broker through an Ingress using TLS
+	// passthrough.
+	Type KafkaExternalListenerType `json:"type"`
+
+	// The domain under which the Ingress hosts are created. The bootstrap host is
+	// '<cluster name>-bootstrap.<domain>' and broker N is '<cluster name>-broker-N.<domain>'.
+	// Required for the (*_ingress_*) type.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// The class of the Ingresses created for the (*_ingress_*) type. If unset, default is
+	// 'nginx'.
+	// +optional
+	Class string `json:"class,omitempty"`
+}
+
+// KafkaMetricsConfigMapRef references a ConfigMap holding a JMX Prometheus exporter configuration
+type KafkaMetricsConfigMapRef struct {
+	// The name of the ConfigMap. It must reside in the namespace of the cluster using it.
+	Name string `json:"name"`
+
+	// The key of the exporter configuration within the ConfigMap. If unset, default is 'metrics'.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
 // KafkaClusterConfig defines options related to the Kafka cluster managed/monitored by Clowder
 type KafkaClusterConfig struct {
 	// Defines the kafka cluster name (default: name of ClowdEnvironment)
@@ -120,6 +158,20 @@ type KafkaClusterConfig struct {
 
 	// Resource Limits
 	Resources strimzi.KafkaSpecKafkaResources `json:"resources,omitempty"`
+
+	// The authentication used by the cluster's TLS listener and the KafkaUsers created for apps.
+	// Valid options are (*_scram-sha-512_*), the default, and (*_tls_*) which issues each app a
+	// client certificate. Ignored when EnableLegacyStrimzi is set.
+	Authentication KafkaClusterAuthentication `json:"authentication,omitempty"`
+
+	// Defines an optional listener exposing the cluster outside of the k8s cluster, for example
+	// so that developers can connect from their workstations. Its bootstrap address is reported
+	// in the ClowdEnvironment status.
+	ExternalListener *KafkaExternalListenerConfig `json:"externalListener,omitempty"`
+
+	// References a ConfigMap whose JMX exporter rules replace the default rules used for the
+	// cluster's metrics.
+	MetricsConfigMapRef *KafkaMetricsConfigMapRef `json:"metricsConfigMapRef,omitempty"`
 }
 
 // KafkaConnectClusterConfig defines options related to the Kafka Connect cluster managed/monitored by Clowder
@@ -139,6 +191,44 @@ type KafkaConnectClusterConfig struct {
 
 	// Image. If unset, default is 'quay.io/cloudservices/xjoin-kafka-connect-strimzi:latest'
 	Image string `json:"image,omitempty"`
+
+	// References a ConfigMap whose JMX exporter rules replace the default rules used for the
+	// connect cluster's metrics.
+	MetricsConfigMapRef *KafkaMetricsConfigMapRef `json:"metricsConfigMapRef,omitempty"`
+}
+
+// KafkaTopicRetentionPolicy details what happens to a KafkaTopic once no ClowdApp references it
+// +kubebuilder:validation:Enum=retain;delete;delete-after-duration
+type KafkaTopicRetentionPolicy string
+
+// KafkaTopicRetentionConfig defines how KafkaTopics which are no longer referenced by any ClowdApp
+// in the environment are cleaned up
+type KafkaTopicRetentionConfig struct {
+	// The policy applied to unreferenced topics. Valid options are: (*_retain_*), the default,
+	// which leaves the topic in place, (*_delete_*) which deletes the topic as soon as the last
+	// ClowdApp stops referencing it, and (*_delete-after-duration_*) which deletes the topic once
+	// it has been unreferenced for the given duration.
+	Policy KafkaTopicRetentionPolicy `json:"policy,omitempty"`
+
+	// The length of time a topic must be unreferenced before it is deleted, e.g. '72h'. Only used
+	// with the (*_delete-after-duration_*) policy.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// KafkaLocalNodePortConfig defines an optional NodePort listener for the (*_local-kraft_*) Kafka
+// instance so that it can be reached from outside the cluster
+type KafkaLocalNodePortConfig struct {
+	// The NodePort on which the external listener is exposed. If unset, no external listener is
+	// created.
+	// +optional
+	// +kubebuilder:validation:Minimum:=30000
+	// +kubebuilder:validation:Maximum:=32767
+	Port int32 `json:"port,omitempty"`
+
+	// The hostname advertised to clients connecting through the external listener. If unset,
+	// default is 'localhost'.
+	// +optional
+	Host string `json:"host,omitempty"`
 }
 
 // NamespacedName type to represent a real Namespaced Name
@@ -158,8 +248,9 @@ type KafkaConfig struct {
 	// KafkaTopic CRs and place them in the Kafka cluster's namespace described in the configuration,
 	// (*_app-interface_*) which simply passes the topic names through to the App's
 	// cdappconfig.json and expects app-interface to have created the relevant
-	// topics, and (*_local_*) where a small instance of Kafka is created in the desired cluster namespace
-	// and configured to auto-create topics.
+	// topics, (*_local_*) where a small instance of Kafka is created in the desired cluster namespace
+	// and configured to auto-create topics, and (*_local-kraft_*) where a single-node KRaft Kafka
+	// without Zookeeper is created and each requested topic is created explicitly.
 	Mode KafkaMode `json:"mode"`
 
 	// EnableLegacyStrimzi disables TLS + user auth
@@ -178,6 +269,25 @@ type KafkaConfig struct {
 	// Defines the secret reference for the Managed Kafka mode. Only used in (*_managed_*) mode.
 	ManagedSecretRef NamespacedName `json:"managedSecretRef,omitempty"`
 
+	// Defines an optional NodePort listener for the local Kafka instance. Only used in
+	// (*_local-kraft_*) mode.
+	LocalNodePort KafkaLocalNodePortConfig `json:"localNodePort,omitempty"`
+
+	// Defines the default quotas applied to each app's KafkaUser. An app may override each quota
+	// individually. Only used in (*_operator_*) mode.
+	Quotas strimzi.KafkaUserSpecQuotas `json:"quotas,omitempty"`
+
+	// A Go template used to build the actual name of each topic requested by an app, e.g.
+	// '{{.Env}}.{{.Topic}}'. The template is given the requested topic name as .Topic, the
+	// environment name as .Env and the app's namespace as .Namespace. If unset, each mode uses
+	// its default naming. Used in all modes; in (*_managed_*) mode any topicMapping in the
+	// managed secret takes precedence.
+	TopicNamingTemplate string `json:"topicNamingTemplate,omitempty"`
+
+	// Defines what happens to a KafkaTopic once no ClowdApp in the environment references it.
+	// Only used in (*_operator_*) mode.
+	TopicRetention KafkaTopicRetentionConfig `json:"topicRetention,omitempty"`
+
 	// (Deprecated) Defines the cluster name to be used by the Kafka Provider this will
 	// be used in some modes to locate the Kafka instance.
 	ClusterName string `json:"clusterName,omitempty"`
@@ -217,10 +327,8 @@ type DatabaseConfig struct {
 	PVC bool `json:"pvc,omitempty"`
 }
 
-// TODO: Other potential modes: splunk, kafka
-
 // LoggingMode details the mode of operation of the Clowder Logging Provider
-// +kubebuilder:validation:Enum=app-interface;null;none
+// +kubebuilder:validation:Enum=app-interface;loki;splunk;kafka;null;none
 type LoggingMode string
 
 // LoggingConfig configures the Clowder provider controlling the creation of
@@ -228,10 +336,75 @@ type LoggingMode string
 type LoggingConfig struct {
 	// The mode of operation of the Clowder Logging Provider. Valid options are:
 	// (*_app-interface_*) where the provider will pass through cloudwatch credentials
-	// to the app configuration, and (*_none_*) where no logging will be configured.
+	// to the app configuration, (*_loki_*) where a local Loki instance is deployed
+	// for the environment, (*_splunk_*) where the provider will pass through the
+	// Splunk HEC details from a secret, (*_kafka_*) where apps are given a topic
+	// to produce their logs to, and (*_none_*) where no logging will be configured.
 	Mode LoggingMode `json:"mode"`
+
+	// Defines the Splunk HEC used in (*_splunk_*) mode.
+	Splunk LoggingSplunkConfig `json:"splunk,omitempty"`
+
+	// Defines the topic used in (*_kafka_*) mode.
+	Kafka LoggingKafkaConfig `json:"kafka,omitempty"`
+
+	// The default level and format apps should log with, which apps and their
+	// deployments may override.
+	LogSettings `json:",inline"`
+}
+
+// LogLevel details the level apps should log at, one of 'debug', 'info', 'warning' or 'error'
+// +kubebuilder:validation:Enum=debug;info;warning;error
+type LogLevel string
+
+// LogFormat details the format apps should log in, one of 'json' or 'text'
+// +kubebuilder:validation:Enum=json;text
+type LogFormat string
+
+// LogSettings defines the level and format apps should log with. The settings are passed to the
+// apps in the logging configuration, and are not applied by Clowder itself.
+type LogSettings struct {
+	// The level apps should log at.
+	// +optional
+	Level LogLevel `json:"level,omitempty"`
+
+	// The format apps should log in.
+	// +optional
+	Format LogFormat `json:"format,omitempty"`
 }
 
+// LoggingSplunkConfig configures the Splunk HEC used in (*_splunk_*) logging mode.
+type LoggingSplunkConfig struct {
+	// Defines the secret holding the HEC details, with the 'url' and 'token'
+	// keys and an optional 'index'.
+	SecretRef NamespacedName `json:"secretRef,omitempty"`
+}
+
+// LoggingKafkaConfig configures the topic used in (*_kafka_*) logging mode.
+type LoggingKafkaConfig struct {
+	// The requested name of the topic apps produce their logs to, which is
+	// subject to the same naming as the apps' own topics. If unset, default is
+	// 'platform.logging'.
+	// +optional
+	TopicName string `json:"topicName,omitempty"`
+
+	// The requested number of partitions for the topic. If unset, the Kafka
+	// provider's default is used.
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	Partitions int32 `json:"partitions,omitempty"`
+
+	// The requested number of replicas for the topic. If unset, the Kafka
+	// provider's default is used.
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	Replicas int32 `json:"replicas,omitempty"`
+}
+
+// DefaultLoggingTopicName is the requested name of the topic apps produce their logs to in
+// (*_kafka_*) logging mode when none is configured.
+const DefaultLoggingTopicName = "platform.logging"
+
 // ServiceMeshMode just determines if we enable or disable the service mesh
 // +kubebuilder:validation:Enum=enabled;disabled
 type ServiceMeshMode string
@@ -242,11 +415,9 @@ type ServiceMeshConfig struct {
 	Mode ServiceMeshMode `json:"mode,omitempty"`
 }
 
-// TODO: Other potential mode: ceph, S3
-
 // ObjectStoreMode details the mode of operation of the Clowder ObjectStore
 // Provider
-// +kubebuilder:validation:Enum=minio;app-interface;none
+// +kubebuilder:validation:Enum=minio;app-interface;s3;none
 type ObjectStoreMode string
 
 // ObjectStoreConfig configures the Clowder provider controlling the creation of
@@ -254,16 +425,29 @@ type ObjectStoreMode string
 type ObjectStoreConfig struct {
 	// The mode of operation of the Clowder ObjectStore Provider. Valid options are:
 	// (*_app-interface_*) where the provider will pass through Amazon S3 credentials
-	// to the app configuration, and (*_minio_*) where a local Minio instance will
-	// be created.
+	// to the app configuration, (*_minio_*) where a local Minio instance will
+	// be created, and (*_s3_*) where buckets will be created in an existing
+	// S3-compatible object store, such as Ceph RGW, described by secretRef.
 	Mode ObjectStoreMode `json:"mode"`
 
+	// Defines the secret reference for the S3-compatible object store. Only used in
+	// (*_s3_*) mode.
+	SecretRef NamespacedName `json:"secretRef,omitempty"`
+
 	// Currently unused.
 	Suffix string `json:"suffix,omitempty"`
 
 	// If using the (*_local_*) mode and PVC is set to true, this instructs the local
 	// Database instance to use a PVC instead of emptyDir for its volumes.
 	PVC bool `json:"pvc,omitempty"`
+
+	// A Go template used to build the actual name of each bucket requested by an app, e.g.
+	// '{{.Namespace}}-{{.Bucket}}', so that apps requesting the same bucket name do not share
+	// data. The template is given the requested bucket name as .Bucket, the environment name as
+	// .Env and the app's namespace as .Namespace. The requested name is still presented to the
+	// app as requestedName. In (*_minio_*) mode buckets are created with the templated name; in
+	// (*_app-interface_*) mode a bucket with exactly the templated name must exist.
+	BucketNamingTemplate string `json:"bucketNamingTemplate,omitempty"`
 }
 
 // FeatureFlagsMode details the mode of operation of the Clowder FeatureFlags
@@ -296,6 +480,26 @@ type FeatureFlagsConfig struct {
 	Port int32 `json:"port,omitempty"`
 }
 
+// SchemaRegistryMode details the mode of operation of the Clowder SchemaRegistry
+// Provider
+// +kubebuilder:validation:Enum=local;app-interface;none
+// +kubebuilder:validation:Optional
+type SchemaRegistryMode string
+
+// SchemaRegistryConfig configures the Clowder provider controlling the creation of
+// Kafka schema registry instances.
+type SchemaRegistryConfig struct {
+	// The mode of operation of the Clowder SchemaRegistry Provider. Valid options are:
+	// (*_app-interface_*) where the provider will pass through the URL and credentials
+	// to the app configuration, and (*_local_*) where a local Apicurio registry instance
+	// will be created.
+	Mode SchemaRegistryMode `json:"mode,omitempty"`
+
+	// Defines the secret containing the registry url, username and password, only used
+	// for (*_app-interface_*) mode.
+	CredentialRef NamespacedName `json:"credentialRef,omitempty"`
+}
+
 // InMemoryMode details the mode of operation of the Clowder InMemoryDB
 // Provider
 // +kubebuilder:validation:Enum=redis;app-interface;elasticache;none
@@ -395,6 +599,9 @@ type ProvidersConfig struct {
 	// Defines the Configuration for the Clowder ServiceMesh Provider.
 	ServiceMesh ServiceMeshConfig `json:"serviceMesh,omitempty"`
 
+	// Defines the Configuration for the Clowder SchemaRegistry Provider.
+	SchemaRegistry SchemaRegistryConfig `json:"schemaRegistry,omitempty"`
+
 	// Defines the pull secret to use for the service accounts.
 	PullSecrets []NamespacedName `json:"pullSecrets,omitempty"`
 
@@ -424,6 +631,40 @@ type ClowdEnvironmentStatus struct {
 	Deployments     common.DeploymentStatus `json:"deployments,omitempty"`
 	Apps            []AppInfo               `json:"apps,omitempty"`
 	Generation      int64                   `json:"generation,omitempty"`
+	// KafkaExternalBootstrapServers is the bootstrap address of the Kafka cluster's external
+	// listener, if one is configured.
+	KafkaExternalBootstrapServers string `json:"kafkaExternalBootstrapServers,omitempty"`
+	// KafkaTopicConflicts lists the KafkaTopics whose settings were requested with differing
+	// values by multiple ClowdApps in the environment.
+	KafkaTopicConflicts []KafkaTopicConflictStatus `json:"kafkaTopicConflicts,omitempty"`
+}
+
+// KafkaTopicConflictStatus details a KafkaTopic whose settings were requested with differing
+// values by multiple ClowdApps, and the settings that were applied to it.
+type KafkaTopicConflictStatus struct {
+	// The name of the KafkaTopic.
+	TopicName string `json:"topicName"`
+
+	// The number of partitions applied to the KafkaTopic.
+	Partitions int32 `json:"partitions"`
+
+	// The number of replicas applied to the KafkaTopic.
+	Replicas int32 `json:"replicas"`
+
+	// The config applied to the KafkaTopic.
+	Config map[string]string `json:"config,omitempty"`
+
+	// The settings which were requested with differing values.
+	Conflicts []KafkaTopicSettingConflict `json:"conflicts"`
+}
+
+// KafkaTopicSettingConflict details a single topic setting requested with differing values.
+type KafkaTopicSettingConflict struct {
+	// The setting, either 'partitions', 'replicas' or a topic config key.
+	Key string `json:"key"`
+
+	// A map of the names of the contributing ClowdApps to the values they requested.
+	Values map[string]string `json:"values"`
 }
 
 // AppInfo details information about a specific app.
@@ -550,6 +791,24 @@ func (i *ClowdEnvironment) ConvertDeprecatedKafkaSpec() {
 	}
 }
 
+// GetLoggingTopicName returns the requested name of the topic apps produce their logs to in
+// (*_kafka_*) logging mode.
+func (i *ClowdEnvironment) GetLoggingTopicName() string {
+	if i.Spec.Providers.Logging.Kafka.TopicName == "" {
+		return DefaultLoggingTopicName
+	}
+	return i.Spec.Providers.Logging.Kafka.TopicName
+}
+
+// UsesKafkaLogging returns whether the app produces its logs to the logging topic. In (*_kafka_*)
+// logging mode, only apps requesting topics of their own, which have not opted out, do so.
+func (i *ClowdEnvironment) UsesKafkaLogging(app *ClowdApp) bool {
+	if i.Spec.Providers.Logging.Mode != "kafka" || len(app.Spec.KafkaTopics) == 0 {
+		return false
+	}
+	return app.Spec.Logging == nil || !app.Spec.Logging.DisableKafka
+}
+
 // GetAppsInEnv populates the appList with a list of all apps in the ClowdEnvironment.
 func (i *ClowdEnvironment) GetAppsInEnv(ctx context.Context, pClient client.Client) (*ClowdAppList, error) {
 
@@ -55,6 +55,9 @@ type CyndiPipelineSpec struct {
 	// +optional
 	// +kubebuilder:validation:MinLength:=1
 	InventoryDbSecret *string `json:"inventoryDbSecret,omitempty"`
+
+	// +optional
+	AdditionalFilters []map[string]string `json:"additionalFilters,omitempty"`
 }
 
 // CyndiPipelineStatus defines the observed state of CyndiPipeline
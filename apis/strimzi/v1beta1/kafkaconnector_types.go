@@ -0,0 +1,104 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KafkaConnectorSpec defines the desired state of KafkaConnector
+type KafkaConnectorSpec struct {
+	// The Class for the Kafka Connector.
+	Class string `json:"class,omitempty"`
+
+	// The Kafka Connector configuration. The following properties cannot be set:
+	// connector.class, tasks.max.
+	Config map[string]string `json:"config,omitempty"`
+
+	// Whether the connector should be paused. Defaults to false.
+	Pause *bool `json:"pause,omitempty"`
+
+	// The maximum number of tasks for the Kafka Connector.
+	TasksMax *int32 `json:"tasksMax,omitempty"`
+}
+
+// KafkaConnectorStatusCondition is a condition reported by the strimzi operator.
+type KafkaConnectorStatusCondition struct {
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+	Message            string `json:"message,omitempty"`
+	Reason             string `json:"reason,omitempty"`
+	Status             string `json:"status,omitempty"`
+	Type               string `json:"type,omitempty"`
+}
+
+// KafkaConnectorState is the state of a connector or one of its tasks as reported by the Kafka
+// Connect REST API.
+type KafkaConnectorState struct {
+	ID       int32  `json:"id,omitempty"`
+	State    string `json:"state,omitempty"`
+	Trace    string `json:"trace,omitempty"`
+	WorkerID string `json:"worker_id,omitempty"`
+}
+
+// KafkaConnectorConnectorStatus is the connector status as reported by the Kafka Connect REST
+// API.
+type KafkaConnectorConnectorStatus struct {
+	Connector KafkaConnectorState   `json:"connector,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Tasks     []KafkaConnectorState `json:"tasks,omitempty"`
+	Type      string                `json:"type,omitempty"`
+}
+
+// KafkaConnectorStatus defines the observed state of KafkaConnector
+type KafkaConnectorStatus struct {
+	// List of status conditions.
+	Conditions []KafkaConnectorStatusCondition `json:"conditions,omitempty"`
+
+	// The connector status, as reported by the Kafka Connect REST API.
+	ConnectorStatus *KafkaConnectorConnectorStatus `json:"connectorStatus,omitempty"`
+
+	// The generation of the CRD that was last reconciled by the operator.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The maximum number of tasks for the Kafka Connector.
+	TasksMax int32 `json:"tasksMax,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// KafkaConnector is the Schema for the kafkaconnectors API
+type KafkaConnector struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KafkaConnectorSpec   `json:"spec,omitempty"`
+	Status KafkaConnectorStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KafkaConnectorList contains a list of KafkaConnector
+type KafkaConnectorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KafkaConnector `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KafkaConnector{}, &KafkaConnectorList{})
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
 	"github.com/go-logr/logr"
@@ -48,6 +49,7 @@ import (
 	_ "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/namespace"
 	_ "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/objectstore"
 	_ "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/pullsecrets"
+	_ "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/schemaregistry"
 	_ "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/serviceaccount"
 	_ "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/servicemesh"
 	_ "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/web"
@@ -160,7 +162,7 @@ func (r *ClowdEnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	var requeue = false
 
-	provErr := runProvidersForEnv(log, provider)
+	requeueAfter, provErr := runProvidersForEnv(log, provider)
 
 	if provErr != nil {
 		if non_fatal := errors.HandleError(ctx, provErr); !non_fatal {
@@ -232,18 +234,33 @@ func (r *ClowdEnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		SetClowdEnvConditions(ctx, r.Client, &env, crd.ReconciliationPartiallySuccessful, err)
 	}
 
-	return ctrl.Result{Requeue: requeue}, nil
+	return ctrl.Result{Requeue: requeue, RequeueAfter: requeueAfter}, nil
 }
 
-func runProvidersForEnv(log logr.Logger, provider providers.Provider) error {
+// runProvidersForEnv sets up each provider and runs the environment duty of those which have one,
+// returning the shortest duration after which any of them asked for the environment to be
+// reconciled again.
+func runProvidersForEnv(log logr.Logger, provider providers.Provider) (time.Duration, error) {
+	var requeueAfter time.Duration
+
 	for _, provAcc := range providers.ProvidersRegistration.Registry {
 		log.Info("running provider:", "name", provAcc.Name, "order", provAcc.Order)
-		if _, err := provAcc.SetupProvider(&provider); err != nil {
-			return errors.Wrap(fmt.Sprintf("getprov: %s", provAcc.Name), err)
+		prov, err := provAcc.SetupProvider(&provider)
+		if err != nil {
+			return 0, errors.Wrap(fmt.Sprintf("getprov: %s", provAcc.Name), err)
+		}
+		if envProv, ok := prov.(providers.EnvironmentProvider); ok {
+			after, err := envProv.EnvProvide()
+			if err != nil {
+				return 0, errors.Wrap(fmt.Sprintf("runenv: %s", provAcc.Name), err)
+			}
+			if after > 0 && (requeueAfter == 0 || after < requeueAfter) {
+				requeueAfter = after
+			}
 		}
 		log.Info("running provider: complete", "name", provAcc.Name, "order", provAcc.Order)
 	}
-	return nil
+	return requeueAfter, nil
 }
 
 // SetupWithManager sets up with manager
@@ -28,6 +28,9 @@ type ClowderConfig struct {
 		WatchStrimziResources       bool `json:"watchStrimziResources"`
 		UseComplexStrimziTopicNames bool `json:"useComplexStrimziTopicNames"`
 	} `json:"features"`
+	Settings struct {
+		KafkaTopicConfigConversions map[string]string `json:"kafkaTopicConfigConversions"`
+	} `json:"settings"`
 }
 
 func getConfig() ClowderConfig {
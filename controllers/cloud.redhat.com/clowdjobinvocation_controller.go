@@ -36,6 +36,7 @@ import (
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/iqe"
 	jobProvider "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/job"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/kafka"
 
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
 
@@ -228,6 +229,7 @@ func (r *ClowdJobInvocationReconciler) InvokeJob(cache *providers.ObjectCache, j
 	}
 
 	jobProvider.CreateJobResource(cji, env, nn, job, &j)
+	kafka.MountKafkaUserCert(env, app, &j.Spec.Template.Spec)
 
 	if err := cache.Update(ClowdJob, &j); err != nil {
 		return err
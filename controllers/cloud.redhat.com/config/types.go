@@ -63,6 +63,9 @@ type BrokerConfig struct {
 	// Hostname corresponds to the JSON schema field "hostname".
 	Hostname string `json:"hostname"`
 
+	// Mtls corresponds to the JSON schema field "mtls".
+	Mtls *KafkaMTLSConfig `json:"mtls,omitempty"`
+
 	// Port corresponds to the JSON schema field "port".
 	Port *int `json:"port,omitempty"`
 
@@ -135,6 +138,20 @@ type DependencyEndpoint struct {
 	Port int `json:"port"`
 }
 
+// Deployment logging configuration
+type DeploymentLoggingConfig struct {
+	// Defines the format the deployment should log in, either json or text.
+	Format *string `json:"format,omitempty"`
+
+	// Defines the level the deployment should log at, one of debug, info, warning or
+	// error.
+	Level *string `json:"level,omitempty"`
+
+	// The name of the deployment, as given to its pods in the ACG_DEPLOYMENT
+	// environment variable.
+	Name string `json:"name"`
+}
+
 // Feature Flags Configuration
 type FeatureFlagsConfig struct {
 	// Defines the client access token to use when connect to the FeatureFlags server
@@ -149,6 +166,10 @@ type FeatureFlagsConfig struct {
 
 // In Memory DB Configuration
 type InMemoryDBConfig struct {
+	// Details if the In Memory DB server runs in cluster mode, in which case the
+	// hostname is the configuration endpoint of the cluster.
+	ClusterMode *bool `json:"clusterMode,omitempty"`
+
 	// Defines the hostname for the In Memory DB server configuration.
 	Hostname string `json:"hostname"`
 
@@ -158,6 +179,9 @@ type InMemoryDBConfig struct {
 	// Defines the port for the In Memory DB server configuration.
 	Port int `json:"port"`
 
+	// Details if the In Memory DB server uses TLS.
+	Tls *bool `json:"tls,omitempty"`
+
 	// Defines the username for the In Memory DB server configuration.
 	Username *string `json:"username,omitempty"`
 }
@@ -167,15 +191,30 @@ type KafkaConfig struct {
 	// Defines the brokers the app should connect to for Kafka services.
 	Brokers []BrokerConfig `json:"brokers"`
 
+	// SchemaRegistry corresponds to the JSON schema field "schemaRegistry".
+	SchemaRegistry *SchemaRegistryConfig `json:"schemaRegistry,omitempty"`
+
 	// Defines a list of the topic configurations available to the application.
 	Topics []TopicConfig `json:"topics"`
 }
 
+// Mutual TLS Configuration for Kafka
+type KafkaMTLSConfig struct {
+	// The path to the client certificate mounted in the pod.
+	CertPath string `json:"certPath"`
+
+	// The path to the client private key mounted in the pod.
+	KeyPath string `json:"keyPath"`
+}
+
 // SASL Configuration for Kafka
 type KafkaSASLConfig struct {
 	// Password corresponds to the JSON schema field "password".
 	Password *string `json:"password,omitempty"`
 
+	// The SASL mechanism to use, e.g. PLAIN or SCRAM-SHA-512.
+	SaslMechanism *string `json:"saslMechanism,omitempty"`
+
 	// Username corresponds to the JSON schema field "username".
 	Username *string `json:"username,omitempty"`
 }
@@ -185,10 +224,41 @@ type LoggingConfig struct {
 	// Cloudwatch corresponds to the JSON schema field "cloudwatch".
 	Cloudwatch *CloudWatchConfig `json:"cloudwatch,omitempty"`
 
+	// Defines the log settings of the app's deployments which override the app's own.
+	Deployments []DeploymentLoggingConfig `json:"deployments,omitempty"`
+
+	// Defines the format the app should log in, either json or text.
+	Format *string `json:"format,omitempty"`
+
+	// Kafka corresponds to the JSON schema field "kafka".
+	Kafka *LoggingKafkaConfig `json:"kafka,omitempty"`
+
+	// Defines the level the app should log at, one of debug, info, warning or error.
+	Level *string `json:"level,omitempty"`
+
+	// Loki corresponds to the JSON schema field "loki".
+	Loki *LokiConfig `json:"loki,omitempty"`
+
+	// Splunk corresponds to the JSON schema field "splunk".
+	Splunk *SplunkConfig `json:"splunk,omitempty"`
+
 	// Defines the type of logging configuration
 	Type string `json:"type"`
 }
 
+// Kafka logging configuration
+type LoggingKafkaConfig struct {
+	// Defines the name of the topic the app should produce its logs to, using the
+	// brokers in the kafka configuration.
+	Topic string `json:"topic"`
+}
+
+// Loki configuration
+type LokiConfig struct {
+	// Defines the URL of the Loki server the app should push its logs to.
+	Url string `json:"url"`
+}
+
 // Object Storage Bucket
 type ObjectStoreBucket struct {
 	// Defines the access key for specificed bucket.
@@ -215,12 +285,19 @@ type ObjectStoreConfig struct {
 	// Buckets corresponds to the JSON schema field "buckets".
 	Buckets []ObjectStoreBucket `json:"buckets,omitempty"`
 
+	// Defines the PEM encoded CA certificate to trust when connecting to the Object
+	// Storage server.
+	Cacert *string `json:"cacert,omitempty"`
+
 	// Defines the hostname for the Object Storage server configuration.
 	Hostname string `json:"hostname"`
 
 	// Defines the port for the Object Storage server configuration.
 	Port int `json:"port"`
 
+	// Defines the region for the Object Storage server configuration.
+	Region *string `json:"region,omitempty"`
+
 	// Defines the secret key for the Object Storage server configuration.
 	SecretKey *string `json:"secretKey,omitempty"`
 
@@ -243,8 +320,36 @@ type PrivateDependencyEndpoint struct {
 	Port int `json:"port"`
 }
 
+// Schema Registry Configuration
+type SchemaRegistryConfig struct {
+	// Defines the password for the schema registry, if authentication is required.
+	Password *string `json:"password,omitempty"`
+
+	// Defines the URL of the schema registry API.
+	Url string `json:"url"`
+
+	// Defines the username for the schema registry, if authentication is required.
+	Username *string `json:"username,omitempty"`
+}
+
+// Splunk configuration
+type SplunkConfig struct {
+	// Defines the index the app should send its logs to.
+	Index *string `json:"index,omitempty"`
+
+	// Defines the HEC token the app should use for sending logs to Splunk.
+	Token string `json:"token"`
+
+	// Defines the URL of the Splunk HTTP Event Collector.
+	Url string `json:"url"`
+}
+
 // Topic Configuration
 type TopicConfig struct {
+	// The consumer group the app is permitted to use when consuming from the
+	// topic.
+	ConsumerGroup *string `json:"consumerGroup,omitempty"`
+
 	// The name of the actual topic on the Kafka server.
 	Name string `json:"name"`
 
@@ -440,6 +545,45 @@ func (j *ObjectStoreBucket) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *SchemaRegistryConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["url"]; !ok || v == nil {
+		return fmt.Errorf("field url: required")
+	}
+	type Plain SchemaRegistryConfig
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = SchemaRegistryConfig(plain)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *KafkaMTLSConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["certPath"]; !ok || v == nil {
+		return fmt.Errorf("field certPath: required")
+	}
+	if v, ok := raw["keyPath"]; !ok || v == nil {
+		return fmt.Errorf("field keyPath: required")
+	}
+	type Plain KafkaMTLSConfig
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = KafkaMTLSConfig(plain)
+	return nil
+}
+
 // UnmarshalJSON implements json.Unmarshaler.
 func (j *DependencyEndpoint) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
@@ -562,6 +706,81 @@ var enumValues_BrokerConfigAuthtype = []interface{}{
 	"sasl",
 }
 
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *DeploymentLoggingConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["name"]; !ok || v == nil {
+		return fmt.Errorf("field name: required")
+	}
+	type Plain DeploymentLoggingConfig
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = DeploymentLoggingConfig(plain)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *LoggingKafkaConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["topic"]; !ok || v == nil {
+		return fmt.Errorf("field topic: required")
+	}
+	type Plain LoggingKafkaConfig
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = LoggingKafkaConfig(plain)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *LokiConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["url"]; !ok || v == nil {
+		return fmt.Errorf("field url: required")
+	}
+	type Plain LokiConfig
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = LokiConfig(plain)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *SplunkConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["token"]; !ok || v == nil {
+		return fmt.Errorf("field token: required")
+	}
+	if v, ok := raw["url"]; !ok || v == nil {
+		return fmt.Errorf("field url: required")
+	}
+	type Plain SplunkConfig
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = SplunkConfig(plain)
+	return nil
+}
+
 // UnmarshalJSON implements json.Unmarshaler.
 func (j *AppConfig) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
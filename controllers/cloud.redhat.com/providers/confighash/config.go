@@ -27,9 +27,10 @@ func (ch *confighashProvider) persistConfig(app *crd.ClowdApp, c *config.AppConf
 		return "", errors.Wrap("Failed to marshal config JSON", err)
 	}
 
-	h := sha256.New()
-	h.Write([]byte(jsonData))
-	hash := fmt.Sprintf("%x", h.Sum(nil))
+	hash, err := getConfigHash(app, c, jsonData)
+	if err != nil {
+		return "", err
+	}
 
 	secret.StringData = map[string]string{
 		"cdappconfig.json": string(jsonData),
@@ -45,3 +46,25 @@ func (ch *confighashProvider) persistConfig(app *crd.ClowdApp, c *config.AppConf
 
 	return hash, err
 }
+
+// getConfigHash returns the hash of the app's config, which restarts the app's pods when it
+// changes. Apps which reload their log settings themselves are not restarted when only those
+// settings change, so they are left out of the hash.
+func getConfigHash(app *crd.ClowdApp, c *config.AppConfig, jsonData []byte) (string, error) {
+	if app.Spec.Logging != nil && app.Spec.Logging.HotReload {
+		hashed := *c
+		hashed.Logging.Level = nil
+		hashed.Logging.Format = nil
+		hashed.Logging.Deployments = nil
+
+		var err error
+		jsonData, err = json.Marshal(hashed)
+		if err != nil {
+			return "", errors.Wrap("Failed to marshal config JSON", err)
+		}
+	}
+
+	h := sha256.New()
+	h.Write(jsonData)
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,33 @@
+package confighash
+
+import (
+	"encoding/json"
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigHashHotReload(t *testing.T) {
+	assert := assert.New(t)
+
+	hash := func(app *crd.ClowdApp, level string) string {
+		c := config.AppConfig{Logging: config.LoggingConfig{Type: "null", Level: providers.StrPtr(level)}}
+		jsonData, err := json.Marshal(c)
+		assert.NoError(err)
+		h, err := getConfigHash(app, &c, jsonData)
+		assert.NoError(err)
+		return h
+	}
+
+	app := &crd.ClowdApp{}
+	assert.NotEqual(hash(app, "info"), hash(app, "debug"))
+
+	app.Spec.Logging = &crd.AppLoggingSpec{}
+	assert.NotEqual(hash(app, "info"), hash(app, "debug"))
+
+	app.Spec.Logging.HotReload = true
+	assert.Equal(hash(app, "info"), hash(app, "debug"))
+}
@@ -1,17 +1,24 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	_ "github.com/lib/pq"
 	core "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -20,6 +27,22 @@ var rdsCa string
 
 const caURL string = "https://s3.amazonaws.com/rds-downloads/rds-combined-ca-bundle.pem"
 
+// extensionsQueryTimeout bounds both the connection to the database and the query listing its
+// extensions, so that an unreachable database does not stall the reconcile.
+const extensionsQueryTimeout = 10 * time.Second
+
+// extensionsCacheTTL is how long the extensions found in a database are reused before the
+// database is queried again.
+const extensionsCacheTTL = 10 * time.Minute
+
+type extensionsCacheEntry struct {
+	present map[string]bool
+	expires time.Time
+}
+
+var extensionsCache = map[string]extensionsCacheEntry{}
+var extensionsCacheMutex sync.Mutex
+
 type appInterface struct {
 	providers.Provider
 	Config config.DatabaseConfig
@@ -70,6 +93,7 @@ func NewAppInterfaceDBProvider(p *providers.Provider) (providers.ClowderProvider
 
 func (a *appInterface) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
 	if app.Spec.Database.Name == "" && app.Spec.Database.SharedDBAppName == "" {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.DatabaseExtensionsReady)
 		return nil
 	}
 
@@ -137,9 +161,119 @@ func (a *appInterface) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
 
 	c.Database = &a.Config
 
+	if len(app.Spec.Database.Extensions) > 0 {
+		missing, err := getMissingExtensions(a.Ctx, &a.Config, app.Spec.Database.Extensions)
+		setExtensionsCondition(app, missing, err)
+	} else {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.DatabaseExtensionsReady)
+	}
+
 	return nil
 }
 
+// getMissingExtensions returns the requested extensions which have not been created in the
+// database. The extensions present in each database are cached for extensionsCacheTTL, so the
+// database is not connected to on every reconcile.
+func getMissingExtensions(ctx context.Context, cfg *config.DatabaseConfig, extensions []string) ([]string, error) {
+	key := fmt.Sprintf("%s/%s", net.JoinHostPort(cfg.Hostname, strconv.Itoa(cfg.Port)), cfg.Name)
+
+	extensionsCacheMutex.Lock()
+	entry, ok := extensionsCache[key]
+	extensionsCacheMutex.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		present, err := queryExtensions(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		entry = extensionsCacheEntry{present: present, expires: time.Now().Add(extensionsCacheTTL)}
+
+		extensionsCacheMutex.Lock()
+		extensionsCache[key] = entry
+		extensionsCacheMutex.Unlock()
+	}
+
+	missing := []string{}
+	for _, extension := range extensions {
+		if !entry.present[extension] {
+			missing = append(missing, extension)
+		}
+	}
+
+	return missing, nil
+}
+
+// queryExtensions connects to the database and returns the set of extensions created in it.
+func queryExtensions(ctx context.Context, cfg *config.DatabaseConfig) (map[string]bool, error) {
+	query := url.Values{}
+	query.Set("sslmode", cfg.SslMode)
+	query.Set("connect_timeout", strconv.Itoa(int(extensionsQueryTimeout.Seconds())))
+	if cfg.RdsCa != nil {
+		query.Set("sslinline", "true")
+		query.Set("sslrootcert", *cfg.RdsCa)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Hostname, strconv.Itoa(cfg.Port)),
+		Path:     cfg.Name,
+		RawQuery: query.Encode(),
+	}
+
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, errors.Wrap("Failed to open database connection", err)
+	}
+	defer db.Close()
+
+	queryCtx, cancel := context.WithTimeout(ctx, extensionsQueryTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(queryCtx, "SELECT extname FROM pg_extension")
+	if err != nil {
+		return nil, errors.Wrap("Failed to list database extensions", err)
+	}
+	defer rows.Close()
+
+	present := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.Wrap("Failed to read database extensions", err)
+		}
+		present[name] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap("Failed to read database extensions", err)
+	}
+
+	return present, nil
+}
+
+func setExtensionsCondition(app *crd.ClowdApp, missing []string, err error) {
+	condition := crd.ClowdCondition{Type: crd.DatabaseExtensionsReady}
+
+	switch {
+	case err != nil:
+		condition.Status = core.ConditionUnknown
+		condition.Reason = "ValidationFailed"
+		condition.Message = err.Error()
+	case len(missing) > 0:
+		condition.Status = core.ConditionFalse
+		condition.Reason = "MissingExtensions"
+		condition.Message = fmt.Sprintf("Missing database extensions: %s", strings.Join(missing, ", "))
+	default:
+		condition.Status = core.ConditionTrue
+		condition.Reason = "ExtensionsPresent"
+		condition.Message = "All requested database extensions are present"
+	}
+
+	crd.UpdateClowdAppCondition(&app.Status, &condition)
+}
+
 func resolveDb(spec crd.DatabaseSpec, c []config.DatabaseConfig) config.DatabaseConfig {
 	for _, config := range c {
 		hostname := strings.Split(config.Hostname, ".")[0]
@@ -1,10 +1,13 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
 	core "k8s.io/api/core/v1"
 )
 
@@ -39,3 +42,64 @@ func TestAppInterfaceDb(t *testing.T) {
 		t.Error("resolveDb did not match given config")
 	}
 }
+
+func TestAppInterfaceDbExtensionsCondition(t *testing.T) {
+	app := crd.ClowdApp{}
+
+	setExtensionsCondition(&app, []string{"pg_trgm", "uuid-ossp"}, nil)
+
+	if len(app.Status.Conditions) != 1 {
+		t.Fatalf("Wrong number of conditions %d; expected 1", len(app.Status.Conditions))
+	}
+
+	condition := app.Status.Conditions[0]
+	if condition.Type != crd.DatabaseExtensionsReady || condition.Status != core.ConditionFalse {
+		t.Errorf("Condition was not set to false: %v", condition)
+	}
+	if condition.Message != "Missing database extensions: pg_trgm, uuid-ossp" {
+		t.Errorf("Missing extensions were not reported: %s", condition.Message)
+	}
+
+	setExtensionsCondition(&app, []string{}, nil)
+
+	if len(app.Status.Conditions) != 1 {
+		t.Fatalf("Wrong number of conditions %d; expected 1", len(app.Status.Conditions))
+	}
+	if app.Status.Conditions[0].Status != core.ConditionTrue {
+		t.Errorf("Condition was not updated to true: %v", app.Status.Conditions[0])
+	}
+}
+
+func TestAppInterfaceDbExtensionsConditionRemoved(t *testing.T) {
+	app := crd.ClowdApp{}
+	setExtensionsCondition(&app, []string{"pg_trgm"}, nil)
+
+	a := appInterface{}
+	if err := a.Provide(&app, &config.AppConfig{}); err != nil {
+		t.Fatalf("Error providing database: %v", err)
+	}
+
+	if len(app.Status.Conditions) != 0 {
+		t.Errorf("Stale extensions condition was not removed: %v", app.Status.Conditions)
+	}
+}
+
+func TestAppInterfaceDbExtensionsCached(t *testing.T) {
+	// The hostname does not resolve, so the test fails if the database is queried
+	cfg := config.DatabaseConfig{Hostname: "test-db.invalid", Port: 5432, Name: "test-db"}
+
+	extensionsCache["test-db.invalid:5432/test-db"] = extensionsCacheEntry{
+		present: map[string]bool{"pg_trgm": true},
+		expires: time.Now().Add(time.Minute),
+	}
+	defer delete(extensionsCache, "test-db.invalid:5432/test-db")
+
+	missing, err := getMissingExtensions(context.Background(), &cfg, []string{"pg_trgm", "uuid-ossp"})
+
+	if err != nil {
+		t.Fatalf("Cached extensions were not used: %v", err)
+	}
+	if len(missing) != 1 || missing[0] != "uuid-ossp" {
+		t.Errorf("Wrong missing extensions %v; expected [uuid-ossp]", missing)
+	}
+}
@@ -28,6 +28,9 @@ var LocalDBPVC = providers.NewSingleResourceIdent(ProvName, "local_db_pvc", &cor
 // LocalDBSecret is the ident refering to the local DB secret object.
 var LocalDBSecret = providers.NewSingleResourceIdent(ProvName, "local_db_secret", &core.Secret{})
 
+// LocalDBConfigMap is the ident refering to the local DB config map object.
+var LocalDBConfigMap = providers.NewSingleResourceIdent(ProvName, "local_db_config_map", &core.ConfigMap{})
+
 type localDbProvider struct {
 	providers.Provider
 	Config config.DatabaseConfig
@@ -105,6 +108,21 @@ func (db *localDbProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error
 
 	provutils.MakeLocalDB(dd, nn, app, &dbCfg, image, db.Env.Spec.Providers.Database.PVC, app.Spec.Database.Name)
 
+	if len(app.Spec.Database.Extensions) > 0 || len(app.Spec.Database.Parameters) > 0 {
+		cm := &core.ConfigMap{}
+		if err := db.Cache.Create(LocalDBConfigMap, nn, cm); err != nil {
+			return err
+		}
+
+		provutils.MakeLocalDBConfigMap(cm, nn, app, app.Spec.Database.Extensions, app.Spec.Database.Parameters)
+
+		if err = db.Cache.Update(LocalDBConfigMap, cm); err != nil {
+			return err
+		}
+
+		provutils.MountLocalDBConfig(dd, nn, cm)
+	}
+
 	if err = db.Cache.Update(LocalDBDeployment, dd); err != nil {
 		return err
 	}
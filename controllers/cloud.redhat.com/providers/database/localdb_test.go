@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	apps "k8s.io/api/apps/v1"
@@ -154,3 +155,39 @@ func compareEnvs(a, b *([]core.EnvVar)) bool {
 	}
 	return true
 }
+
+func TestLocalDBConfigMap(t *testing.T) {
+	nn, app := getBaseElements()
+
+	cm := core.ConfigMap{}
+	provutils.MakeLocalDBConfigMap(&cm, nn, &app, []string{"pg_trgm", "uuid-ossp"}, map[string]string{
+		"work_mem":        "8MB",
+		"max_connections": "200",
+	})
+
+	conf := "max_connections = '200'\nwork_mem = '8MB'\n"
+	if cm.Data["clowder.conf"] != conf {
+		t.Fatalf("Config %q did not match expected %q", cm.Data["clowder.conf"], conf)
+	}
+
+	script := cm.Data["clowder-extensions.sh"]
+	for _, ext := range []string{`"pg_trgm"`, `"uuid-ossp"`} {
+		if !strings.Contains(script, "CREATE EXTENSION IF NOT EXISTS "+ext+";") {
+			t.Fatalf("Extension %s was not created in script %q", ext, script)
+		}
+	}
+
+	d := apps.Deployment{}
+	provutils.MakeLocalDB(&d, nn, &app, &config.DatabaseConfig{}, "imagename:tag", true, "")
+	provutils.MountLocalDBConfig(&d, nn, &cm)
+
+	if len(d.Spec.Template.Spec.Volumes) != 2 {
+		t.Fatal("Config volume was not added")
+	}
+	if len(d.Spec.Template.Spec.Containers[0].VolumeMounts) != 3 {
+		t.Fatal("Config files were not mounted")
+	}
+	if d.Spec.Template.GetAnnotations()["configHash"] == "" {
+		t.Fatal("Config hash annotation was not set")
+	}
+}
@@ -55,6 +55,12 @@ func initDeployment(app *crd.ClowdApp, env *crd.ClowdEnvironment, d *apps.Deploy
 	envvar := pod.Env
 	envvar = append(envvar, core.EnvVar{Name: "ACG_CONFIG", Value: "/cdapp/cdappconfig.json"})
 
+	// the deployment name lets the app find its deployment's log settings in the config, it is
+	// only set for apps using them so that other apps' pods are left unchanged
+	if app.Spec.Logging != nil || deployment.Logging != nil {
+		envvar = append(envvar, core.EnvVar{Name: "ACG_DEPLOYMENT", Value: deployment.Name})
+	}
+
 	var livenessProbe core.Probe
 	var readinessProbe core.Probe
 
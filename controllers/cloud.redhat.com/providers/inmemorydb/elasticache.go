@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1/common"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
@@ -12,18 +13,23 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const defaultElasticacheSecretName = "in-memory-db"
+
 type elasticache struct {
 	providers.Provider
 	Config config.InMemoryDBConfig
 }
 
 func (e *elasticache) Provide(app *crd.ClowdApp, config *config.AppConfig) error {
-	secretName := "in-memory-db"
-
 	if !app.Spec.InMemoryDB {
 		return nil
 	}
 
+	secretName := app.GetInMemoryDBOptions().SecretName
+	if secretName == "" {
+		secretName = defaultElasticacheSecretName
+	}
+
 	secrets := core.SecretList{}
 	err := e.Client.List(e.Ctx, &secrets, client.InNamespace(app.Namespace))
 
@@ -36,17 +42,12 @@ func (e *elasticache) Provide(app *crd.ClowdApp, config *config.AppConfig) error
 
 	for _, secret := range secrets.Items {
 		if secret.Name == secretName {
-			port, err := strconv.Atoi(string(secret.Data["db.port"]))
-
-			if err != nil {
+			if err := parseElasticacheSecret(&e.Config, secret.Data); err != nil {
 				return errors.Wrap(
-					fmt.Sprintf("failed to parse port from secret '%s' in namespace '%s'", secretName, app.Namespace),
+					fmt.Sprintf("failed to parse secret '%s' in namespace '%s'", secretName, app.Namespace),
 					err,
 				)
 			}
-
-			e.Config.Hostname = string(secret.Data["db.endpoint"])
-			e.Config.Port = port
 			found = true
 			break
 		}
@@ -65,6 +66,42 @@ func (e *elasticache) Provide(app *crd.ClowdApp, config *config.AppConfig) error
 	return nil
 }
 
+// parseElasticacheSecret reads the connection details of an Elasticache instance from the data of
+// its secret. In cluster mode, the secret holds the configuration endpoint of the cluster in
+// db.configuration_endpoint, which is used in place of db.endpoint.
+func parseElasticacheSecret(c *config.InMemoryDBConfig, data map[string][]byte) error {
+	port, err := strconv.Atoi(string(data["db.port"]))
+	if err != nil {
+		return errors.Wrap("failed to parse port", err)
+	}
+
+	c.Hostname = string(data["db.endpoint"])
+	c.Port = port
+
+	if endpoint, ok := data["db.configuration_endpoint"]; ok {
+		c.Hostname = string(endpoint)
+		c.ClusterMode = common.TruePtr()
+	}
+
+	if c.Hostname == "" {
+		return errors.New("no db.endpoint or db.configuration_endpoint")
+	}
+
+	if authToken, ok := data["db.auth_token"]; ok {
+		c.Password = providers.StrPtr(string(authToken))
+	}
+
+	if tls, ok := data["db.tls"]; ok {
+		useTLS, err := strconv.ParseBool(string(tls))
+		if err != nil {
+			return errors.Wrap("failed to parse tls", err)
+		}
+		c.Tls = &useTLS
+	}
+
+	return nil
+}
+
 // NewElasticache returns a new elasticache provider object.
 func NewElasticache(p *providers.Provider) (providers.ClowderProvider, error) {
 	config := config.InMemoryDBConfig{}
@@ -0,0 +1,96 @@
+package inmemorydb
+
+import (
+	"context"
+	errlib "errors"
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getElasticacheTestProvider(secrets ...*core.Secret) *elasticache {
+	builder := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme)
+	for _, secret := range secrets {
+		builder = builder.WithObjects(secret)
+	}
+	return &elasticache{Provider: providers.Provider{Ctx: context.TODO(), Client: builder.Build()}}
+}
+
+func getElasticacheTestApp(spec *crd.InMemoryDBSpec) *crd.ClowdApp {
+	return &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns"},
+		Spec:       crd.ClowdAppSpec{InMemoryDB: true, InMemoryDBOptions: spec},
+	}
+}
+
+func getElasticacheTestSecret(name string, data map[string]string) *core.Secret {
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Data:       map[string][]byte{},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+func TestElasticache(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("defaultSecret", func(t *testing.T) {
+		e := getElasticacheTestProvider(getElasticacheTestSecret("in-memory-db", map[string]string{
+			"db.endpoint": "lovely", "db.port": "6767",
+		}))
+
+		c := config.AppConfig{}
+		assert.NoError(e.Provide(getElasticacheTestApp(nil), &c))
+		assert.Equal("lovely", c.InMemoryDb.Hostname)
+		assert.Equal(6767, c.InMemoryDb.Port)
+		assert.Nil(c.InMemoryDb.Password)
+		assert.Nil(c.InMemoryDb.Tls)
+		assert.Nil(c.InMemoryDb.ClusterMode)
+	})
+
+	t.Run("configuredSecret", func(t *testing.T) {
+		e := getElasticacheTestProvider(getElasticacheTestSecret("my-cache", map[string]string{
+			"db.endpoint":               "node",
+			"db.configuration_endpoint": "cluster",
+			"db.port":                   "6379",
+			"db.auth_token":             "token",
+			"db.tls":                    "true",
+		}))
+
+		c := config.AppConfig{}
+		assert.NoError(e.Provide(getElasticacheTestApp(&crd.InMemoryDBSpec{SecretName: "my-cache"}), &c))
+		assert.Equal("cluster", c.InMemoryDb.Hostname)
+		assert.Equal("token", *c.InMemoryDb.Password)
+		assert.True(*c.InMemoryDb.Tls)
+		assert.True(*c.InMemoryDb.ClusterMode)
+	})
+
+	t.Run("missingSecret", func(t *testing.T) {
+		e := getElasticacheTestProvider(getElasticacheTestSecret("in-memory-db", map[string]string{
+			"db.endpoint": "lovely", "db.port": "6767",
+		}))
+
+		err := e.Provide(getElasticacheTestApp(&crd.InMemoryDBSpec{SecretName: "my-cache"}), &config.AppConfig{})
+		var depErr *errors.MissingDependencies
+		assert.True(errlib.As(err, &depErr))
+	})
+
+	t.Run("invalidTLS", func(t *testing.T) {
+		e := getElasticacheTestProvider(getElasticacheTestSecret("in-memory-db", map[string]string{
+			"db.endpoint": "lovely", "db.port": "6767", "db.tls": "maybe",
+		}))
+
+		assert.Error(e.Provide(getElasticacheTestApp(&crd.InMemoryDBSpec{}), &config.AppConfig{}))
+	})
+}
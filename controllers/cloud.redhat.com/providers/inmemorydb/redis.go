@@ -1,16 +1,22 @@
 package inmemorydb
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"sort"
+	"strings"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
 	obj "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/object"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // RedisDeployment identifies the main redis deployment
@@ -22,6 +28,47 @@ var RedisService = providers.NewSingleResourceIdent(ProvName, "redis_service", &
 // RedisConfigMap identifies the main redis configmap
 var RedisConfigMap = providers.NewSingleResourceIdent(ProvName, "redis_config_map", &core.ConfigMap{})
 
+// RedisSecret identifies the secret holding the main redis passwords
+var RedisSecret = providers.NewSingleResourceIdent(ProvName, "redis_secret", &core.Secret{})
+
+// SharedRedisDeployment identifies the environment's shared redis deployment
+var SharedRedisDeployment = providers.NewSingleResourceIdent(ProvName, "shared_redis_deployment", &apps.Deployment{})
+
+// SharedRedisService identifies the environment's shared redis service
+var SharedRedisService = providers.NewSingleResourceIdent(ProvName, "shared_redis_service", &core.Service{})
+
+// SharedRedisConfigMap identifies the environment's shared redis configmap
+var SharedRedisConfigMap = providers.NewSingleResourceIdent(ProvName, "shared_redis_config_map", &core.ConfigMap{})
+
+// SharedRedisSecret identifies the secret holding the environment's shared redis users
+var SharedRedisSecret = providers.NewSingleResourceIdent(ProvName, "shared_redis_secret", &core.Secret{})
+
+// redisIdents are the resource idents of the objects making up a redis instance
+type redisIdents struct {
+	Deployment providers.ResourceIdent
+	Service    providers.ResourceIdent
+	ConfigMap  providers.ResourceIdent
+	makeFn     func(obj.ClowdObject, providers.ObjectMap, bool, bool)
+}
+
+var dedicatedRedisIdents = redisIdents{
+	Deployment: RedisDeployment,
+	Service:    RedisService,
+	ConfigMap:  RedisConfigMap,
+	makeFn:     makeLocalRedis,
+}
+
+var sharedRedisIdents = redisIdents{
+	Deployment: SharedRedisDeployment,
+	Service:    SharedRedisService,
+	ConfigMap:  SharedRedisConfigMap,
+	makeFn:     makeSharedLocalRedis,
+}
+
+const redisAuthPath = "/usr/local/etc/redis-auth/"
+
+const redisAuthHashAnnotation = "clowder/auth-hash"
+
 type localRedis struct {
 	providers.Provider
 	Config config.InMemoryDBConfig
@@ -32,38 +79,70 @@ func (r *localRedis) Provide(app *crd.ClowdApp, config *config.AppConfig) error
 		return nil
 	}
 
+	if app.GetInMemoryDBOptions().Mode == "shared" {
+		return r.provideSharedUser(app, config)
+	}
+
 	r.Config.Hostname = fmt.Sprintf("%v-redis.%v.svc", app.Name, app.Namespace)
 	r.Config.Port = 6379
 
 	nn := providers.GetNamespacedName(app, "redis")
 
-	configMap := &core.ConfigMap{}
-
-	err := r.Provider.Cache.Create(RedisConfigMap, nn, configMap)
+	dataInit := func() map[string]string {
+		password := utils.RandString(16)
+		return map[string]string{
+			"password":  password,
+			"auth.conf": fmt.Sprintf("requirepass %s\n", password),
+		}
+	}
 
+	secMap, err := providers.MakeOrGetSecret(r.Ctx, app, r.Cache, RedisSecret, nn, dataInit)
 	if err != nil {
+		return errors.Wrap("Couldn't set/get redis secret", err)
+	}
+
+	r.Config.Password = providers.StrPtr((*secMap)["password"])
+
+	if err := makeRedisConfigMap(r.Cache, dedicatedRedisIdents, nn, app); err != nil {
 		return err
 	}
 
-	labeler := utils.MakeLabeler(nn, nil, app)
-	labeler(configMap)
+	config.InMemoryDb = &r.Config
 
-	configMap.Data = map[string]string{"redis.conf": "stop-writes-on-bgsave-error no\n"}
+	return makeRedisComponent(r.Cache, dedicatedRedisIdents, app, r.Env.IsNodePort(), (*secMap)["auth.conf"])
+}
 
-	err = r.Provider.Cache.Update(RedisConfigMap, configMap)
+// provideSharedUser gives the app the credentials of its user on the environment's shared redis
+// instance. The users are created when the environment is reconciled.
+func (r *localRedis) provideSharedUser(app *crd.ClowdApp, config *config.AppConfig) error {
+	nn := providers.GetNamespacedName(r.Env, "redis")
+	username := getRedisUsername(app)
 
-	if err != nil {
+	secret := &core.Secret{}
+
+	// This is a REAL call here, not a cached call as the reconciliation must have been processed
+	// for the environment
+	if err := r.Client.Get(r.Ctx, nn, secret); err != nil && !k8serr.IsNotFound(err) {
 		return err
 	}
 
-	config.InMemoryDb = &r.Config
-
-	objList := []providers.ResourceIdent{
-		RedisDeployment,
-		RedisService,
+	password, ok := secret.Data[getRedisUserKey(username)]
+	if !ok {
+		return &errors.MissingDependencies{
+			MissingDeps: map[string][]string{
+				"in-memory-db-user": {fmt.Sprintf("name: %s, secret: %s/%s", username, nn.Namespace, nn.Name)},
+			},
+		}
 	}
 
-	return providers.CachedMakeComponent(r.Provider.Cache, objList, app, "redis", makeLocalRedis, false, r.Env.IsNodePort())
+	r.Config.Hostname = fmt.Sprintf("%v.%v.svc", nn.Name, nn.Namespace)
+	r.Config.Port = 6379
+	r.Config.Username = providers.StrPtr(username)
+	r.Config.Password = providers.StrPtr(string(password))
+
+	config.InMemoryDb = &r.Config
+
+	return nil
 }
 
 // NewLocalRedis returns a new local redis provider object.
@@ -72,14 +151,161 @@ func NewLocalRedis(p *providers.Provider) (providers.ClowderProvider, error) {
 
 	redisProvider := localRedis{Provider: *p, Config: config}
 
+	appList, err := p.Env.GetAppsInEnv(p.Ctx, p.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	users := getSharedRedisUsers(appList)
+	if len(users) == 0 {
+		return &redisProvider, nil
+	}
+
+	if err := makeSharedRedis(p, users); err != nil {
+		return nil, err
+	}
+
 	return &redisProvider, nil
 }
 
+func getRedisUsername(app *crd.ClowdApp) string {
+	return fmt.Sprintf("%s-%s", app.Namespace, app.Name)
+}
+
+func getRedisUserKey(username string) string {
+	return fmt.Sprintf("user.%s", username)
+}
+
+// getSharedRedisUsers returns the sorted usernames of the apps in the environment using the
+// shared redis instance.
+func getSharedRedisUsers(appList *crd.ClowdAppList) []string {
+	users := []string{}
+	for i := range appList.Items {
+		app := &appList.Items[i]
+		if app.GetDeletionTimestamp() != nil || !app.Spec.InMemoryDB {
+			continue
+		}
+		if app.GetInMemoryDBOptions().Mode == "shared" {
+			users = append(users, getRedisUsername(app))
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// makeSharedRedisAuthConf returns the redis configuration for the users of the shared instance.
+// The default user is kept for administration and the health checks, while app users may only
+// access the keys prefixed with their username, and may not run the dangerous commands, such as
+// FLUSHALL, which affect other apps.
+func makeSharedRedisAuthConf(data map[string][]byte, users []string) string {
+	lines := []string{fmt.Sprintf("user default on >%s ~* +@all", data["password"])}
+	for _, user := range users {
+		lines = append(lines, fmt.Sprintf("user %s on >%s ~%s:* +@all -@dangerous", user, data[getRedisUserKey(user)], user))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// makeSharedRedis creates the environment's shared redis instance, with a user for each of the
+// given apps. Existing passwords are kept, and users are removed once no longer needed.
+func makeSharedRedis(p *providers.Provider, users []string) error {
+	nn := providers.GetNamespacedName(p.Env, "redis")
+
+	secret := &core.Secret{}
+	if err := p.Cache.Create(SharedRedisSecret, nn, secret); err != nil {
+		return err
+	}
+
+	getPassword := func(key string) []byte {
+		if password, ok := secret.Data[key]; ok {
+			return password
+		}
+		return []byte(utils.RandString(16))
+	}
+
+	data := map[string][]byte{"password": getPassword("password")}
+	for _, user := range users {
+		key := getRedisUserKey(user)
+		data[key] = getPassword(key)
+	}
+
+	authConf := makeSharedRedisAuthConf(data, users)
+	data["auth.conf"] = []byte(authConf)
+
+	labeler := utils.MakeLabeler(nn, nil, p.Env)
+	labeler(secret)
+
+	secret.Data = data
+	secret.Type = core.SecretTypeOpaque
+
+	if err := p.Cache.Update(SharedRedisSecret, secret); err != nil {
+		return err
+	}
+
+	if err := makeRedisConfigMap(p.Cache, sharedRedisIdents, nn, p.Env); err != nil {
+		return err
+	}
+
+	return makeRedisComponent(p.Cache, sharedRedisIdents, p.Env, p.Env.IsNodePort(), authConf)
+}
+
+func makeRedisConfigMap(
+	cache *providers.ObjectCache, idents redisIdents, nn types.NamespacedName, o obj.ClowdObject,
+) error {
+	configMap := &core.ConfigMap{}
+
+	if err := cache.Create(idents.ConfigMap, nn, configMap); err != nil {
+		return err
+	}
+
+	labeler := utils.MakeLabeler(nn, nil, o)
+	labeler(configMap)
+
+	configMap.Data = map[string]string{
+		"redis.conf": fmt.Sprintf("stop-writes-on-bgsave-error no\ninclude %sauth.conf\n", redisAuthPath),
+	}
+
+	return cache.Update(idents.ConfigMap, configMap)
+}
+
+// makeRedisComponent creates the redis deployment and service, restarting redis whenever its
+// users change.
+func makeRedisComponent(
+	cache *providers.ObjectCache, idents redisIdents, o obj.ClowdObject, nodePort bool, authConf string,
+) error {
+	objList := []providers.ResourceIdent{
+		idents.Deployment,
+		idents.Service,
+	}
+
+	if err := providers.CachedMakeComponent(cache, objList, o, "redis", idents.makeFn, false, nodePort); err != nil {
+		return err
+	}
+
+	dd := &apps.Deployment{}
+	if err := cache.Get(idents.Deployment, dd); err != nil {
+		return err
+	}
+
+	annotations := dd.Spec.Template.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[redisAuthHashAnnotation] = fmt.Sprintf("%x", sha256.Sum256([]byte(authConf)))
+	dd.Spec.Template.SetAnnotations(annotations)
+
+	return cache.Update(idents.Deployment, dd)
+}
+
 func makeLocalRedis(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool) {
-	nn := providers.GetNamespacedName(o, "redis")
+	makeRedis(o, objMap[RedisDeployment].(*apps.Deployment), objMap[RedisService].(*core.Service), nodePort)
+}
 
-	dd := objMap[RedisDeployment].(*apps.Deployment)
-	svc := objMap[RedisService].(*core.Service)
+func makeSharedLocalRedis(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool) {
+	makeRedis(o, objMap[SharedRedisDeployment].(*apps.Deployment), objMap[SharedRedisService].(*core.Service), nodePort)
+}
+
+func makeRedis(o obj.ClowdObject, dd *apps.Deployment, svc *core.Service, nodePort bool) {
+	nn := providers.GetNamespacedName(o, "redis")
 
 	oneReplica := int32(1)
 
@@ -120,6 +346,12 @@ func makeLocalRedis(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool,
 					Name: nn.Name,
 				},
 			},
+		}}, {
+		Name: "redis-auth",
+		VolumeSource: core.VolumeSource{
+			Secret: &core.SecretVolumeSource{
+				SecretName: nn.Name,
+			},
 		}},
 	}
 
@@ -130,7 +362,17 @@ func makeLocalRedis(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool,
 			"redis-server",
 			"/usr/local/etc/redis/redis.conf",
 		},
-		Env: []core.EnvVar{},
+		Env: []core.EnvVar{{
+			Name: "REDISCLI_AUTH",
+			ValueFrom: &core.EnvVarSource{
+				SecretKeyRef: &core.SecretKeySelector{
+					LocalObjectReference: core.LocalObjectReference{
+						Name: nn.Name,
+					},
+					Key: "password",
+				},
+			},
+		}},
 		Ports: []core.ContainerPort{{
 			Name:          "redis",
 			ContainerPort: 6379,
@@ -140,6 +382,9 @@ func makeLocalRedis(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool,
 		VolumeMounts: []core.VolumeMount{{
 			Name:      nn.Name,
 			MountPath: "/usr/local/etc/redis/",
+		}, {
+			Name:      "redis-auth",
+			MountPath: redisAuthPath,
 		}},
 	}}
 
@@ -5,6 +5,7 @@ import (
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"github.com/stretchr/testify/assert"
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -50,3 +51,65 @@ func TestLocalRedis(t *testing.T) {
 		t.Errorf("Port number is incorrect, got: %v, want: %v", p.Port, 6379)
 	}
 }
+
+func TestLocalRedisAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	env := getRedisTestEnv()
+
+	dd, svc := apps.Deployment{}, core.Service{}
+	objMap := providers.ObjectMap{
+		SharedRedisDeployment: &dd,
+		SharedRedisService:    &svc,
+	}
+	makeSharedLocalRedis(&env, objMap, false, false)
+
+	spec := dd.Spec.Template.Spec
+	assert.Equal("env-redis", spec.Volumes[1].Secret.SecretName)
+	assert.Equal(redisAuthPath, spec.Containers[0].VolumeMounts[1].MountPath)
+	assert.Equal("REDISCLI_AUTH", spec.Containers[0].Env[0].Name)
+	assert.Equal("password", spec.Containers[0].Env[0].ValueFrom.SecretKeyRef.Key)
+}
+
+func TestSharedRedisUsers(t *testing.T) {
+	assert := assert.New(t)
+
+	makeApp := func(name string, spec *crd.InMemoryDBSpec) crd.ClowdApp {
+		return crd.ClowdApp{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec:       crd.ClowdAppSpec{InMemoryDB: true, InMemoryDBOptions: spec},
+		}
+	}
+
+	appList := &crd.ClowdAppList{Items: []crd.ClowdApp{
+		makeApp("b", &crd.InMemoryDBSpec{Mode: "shared"}),
+		makeApp("a", &crd.InMemoryDBSpec{Mode: "shared"}),
+		makeApp("c", &crd.InMemoryDBSpec{}),
+		makeApp("d", nil),
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "e", Namespace: "ns"},
+			Spec:       crd.ClowdAppSpec{InMemoryDBOptions: &crd.InMemoryDBSpec{Mode: "shared"}},
+		},
+	}}
+
+	users := getSharedRedisUsers(appList)
+	assert.Equal([]string{"ns-a", "ns-b"}, users)
+}
+
+func TestSharedRedisAuthConf(t *testing.T) {
+	assert := assert.New(t)
+
+	users := []string{"ns-a", "ns-b"}
+	data := map[string][]byte{
+		"password":  []byte("admin"),
+		"user.ns-a": []byte("pa"),
+		"user.ns-b": []byte("pb"),
+	}
+
+	assert.Equal(
+		"user default on >admin ~* +@all\n"+
+			"user ns-a on >pa ~ns-a:* +@all -@dangerous\n"+
+			"user ns-b on >pb ~ns-b:* +@all -@dangerous\n",
+		makeSharedRedisAuthConf(data, users),
+	)
+}
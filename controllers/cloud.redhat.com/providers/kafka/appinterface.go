@@ -27,19 +27,31 @@ func (a *appInterface) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
 		if err != nil {
 			return err
 		}
+	} else {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.CyndiPipelineValid)
 	}
 
-	if len(app.Spec.KafkaTopics) == 0 {
+	topics := getRequestedTopics(a.Env, app)
+	if len(topics) == 0 {
 		return nil
 	}
 
-	for _, topic := range app.Spec.KafkaTopics {
+	for _, topic := range topics {
+		name := topic.TopicName
+		templatedName, ok, err := getTemplatedTopicName(topic.TopicName, a.Env, app.Namespace)
+		if err != nil {
+			return err
+		}
+		if ok {
+			name = templatedName
+		}
+
 		topicName := types.NamespacedName{
 			Namespace: getKafkaNamespace(a.Env),
-			Name:      topic.TopicName,
+			Name:      name,
 		}
 
-		err := validateKafkaTopic(a.Ctx, a.Client, topicName)
+		err = validateKafkaTopic(a.Ctx, a.Client, topicName)
 
 		if err != nil {
 			return err
@@ -48,7 +60,7 @@ func (a *appInterface) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
 		a.Config.Topics = append(
 			a.Config.Topics,
 			config.TopicConfig{
-				Name:          topic.TopicName,
+				Name:          name,
 				RequestedName: topic.TopicName,
 			},
 		)
@@ -0,0 +1,336 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	strimziconnect "cloud.redhat.com/clowder/v2/apis/strimzi/v1beta1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
+	core "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KafkaConnector is the resource ident for the KafkaConnector objects declared by an app.
+var KafkaConnector = providers.NewMultiResourceIdent(ProvName, "kafka_connector", &strimziconnect.KafkaConnector{})
+
+// KafkaConnectorDBSecret is the resource ident for the secret holding the database passwords
+// referenced by an app's connectors.
+var KafkaConnectorDBSecret = providers.NewSingleResourceIdent(ProvName, "kafka_connector_db_secret", &core.Secret{})
+
+// KafkaConnectorDBSecretRole is the resource ident for the role permitting the KafkaConnect cluster
+// to read an app's connector database secret.
+var KafkaConnectorDBSecretRole = providers.NewSingleResourceIdent(ProvName, "kafka_connector_db_secret_role", &rbac.Role{})
+
+// KafkaConnectorDBSecretRoleBinding is the resource ident for the binding of the connector database
+// secret role to the KafkaConnect cluster's service account.
+var KafkaConnectorDBSecretRoleBinding = providers.NewSingleResourceIdent(ProvName, "kafka_connector_db_secret_role_binding", &rbac.RoleBinding{})
+
+// dbPlaceholderPrefix is the prefix of the placeholders which are replaced with the app's own
+// database credentials in a connector config.
+const dbPlaceholderPrefix = "${db."
+
+// connectSecretConfigProvider is the KafkaConnect config provider, shipped with Strimzi 0.24.0 and
+// later, which resolves ${secrets:<namespace>/<name>:<key>} placeholders from k8s secrets when a
+// connector is started, so that secret values never appear in a KafkaConnector CR.
+const connectSecretConfigProvider = "io.strimzi.kafka.KubernetesSecretConfigProvider"
+
+// getConnectorName returns the name of the KafkaConnector for one of an app's connectors. All the
+// environment's connectors share the connect namespace, so the name is scoped by the app's
+// namespace, so that apps of the same name in different namespaces do not share connectors.
+func getConnectorName(app *crd.ClowdApp, connector crd.KafkaConnectorSpec) string {
+	return fmt.Sprintf("%s-%s-%s", app.Namespace, app.Name, connector.Name)
+}
+
+func getSecretPlaceholder(nn types.NamespacedName, key string) string {
+	return fmt.Sprintf("${secrets:%s/%s:%s}", nn.Namespace, nn.Name, key)
+}
+
+// renderConnectorConfig returns a copy of the connector config with the database placeholders
+// replaced by the values from the app's database config. The passwords are not written into the
+// config, they are replaced by references to the keys of the app's connector database secret.
+func renderConnectorConfig(connectorConfig map[string]string, db *config.DatabaseConfig, secretNN types.NamespacedName) (map[string]string, error) {
+	var replacer *strings.Replacer
+	if db != nil {
+		replacer = strings.NewReplacer(
+			"${db.hostname}", db.Hostname,
+			"${db.port}", strconv.Itoa(db.Port),
+			"${db.name}", db.Name,
+			"${db.username}", db.Username,
+			"${db.password}", getSecretPlaceholder(secretNN, "password"),
+			"${db.adminUsername}", db.AdminUsername,
+			"${db.adminPassword}", getSecretPlaceholder(secretNN, "adminPassword"),
+			"${db.sslMode}", db.SslMode,
+		)
+	}
+
+	rendered := map[string]string{}
+	for key, value := range connectorConfig {
+		if replacer != nil {
+			value = replacer.Replace(value)
+		}
+		if strings.Contains(value, dbPlaceholderPrefix) {
+			if db == nil {
+				return nil, errors.New(fmt.Sprintf("connector config key %s references a database but the app has none", key))
+			}
+			return nil, errors.New(fmt.Sprintf("connector config key %s contains an unknown database placeholder", key))
+		}
+		rendered[key] = value
+	}
+
+	return rendered, nil
+}
+
+func (s *strimziProvider) processConnectors(app *crd.ClowdApp, c *config.AppConfig) error {
+	if len(app.Spec.KafkaConnectors) == 0 {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.KafkaConnectorsReady)
+		return nil
+	}
+
+	secretNN := providers.GetNamespacedName(app, "connect-db")
+
+	if c.Database != nil {
+		if err := s.createConnectorDBSecret(app, c.Database, secretNN); err != nil {
+			return err
+		}
+	}
+
+	connectors := []strimziconnect.KafkaConnector{}
+
+	for _, connector := range app.Spec.KafkaConnectors {
+		nn := types.NamespacedName{
+			Namespace: getConnectNamespace(s.Env),
+			Name:      getConnectorName(app, connector),
+		}
+
+		rendered, err := renderConnectorConfig(connector.Config, c.Database, secretNN)
+		if err != nil {
+			return errors.Wrap(fmt.Sprintf("could not render connector %s", connector.Name), err)
+		}
+
+		k := &strimziconnect.KafkaConnector{}
+		if err := s.Cache.Create(KafkaConnector, nn, k); err != nil {
+			return err
+		}
+
+		tasksMax := connector.TasksMax
+		if tasksMax < 1 {
+			tasksMax = 1
+		}
+
+		k.SetName(nn.Name)
+		k.SetNamespace(nn.Namespace)
+		k.SetLabels(providers.Labels{
+			"app":                app.Name,
+			"env":                s.Env.Name,
+			"strimzi.io/cluster": getConnectClusterName(s.Env),
+		})
+		// it would be best for the ClowdApp to own this, but since cross-namespace OwnerReferences
+		// are not permitted, make this owned by the ClowdEnvironment
+		k.SetOwnerReferences([]metav1.OwnerReference{s.Env.MakeOwnerReference()})
+		k.Spec = strimziconnect.KafkaConnectorSpec{
+			Class:    connector.Class,
+			TasksMax: &tasksMax,
+			Config:   rendered,
+		}
+
+		if err := s.Cache.Update(KafkaConnector, k); err != nil {
+			return err
+		}
+
+		connectors = append(connectors, *k)
+	}
+
+	setConnectorCondition(app, connectors)
+
+	return nil
+}
+
+// createConnectorDBSecret creates a secret in the app's namespace holding the database passwords
+// referenced by its connectors, along with a role and binding permitting only the service account
+// of the KafkaConnect cluster to read it.
+func (s *strimziProvider) createConnectorDBSecret(app *crd.ClowdApp, db *config.DatabaseConfig, nn types.NamespacedName) error {
+	labeler := utils.GetCustomLabeler(nil, nn, app)
+
+	secret := &core.Secret{}
+	if err := s.Cache.Create(KafkaConnectorDBSecret, nn, secret); err != nil {
+		return err
+	}
+
+	labeler(secret)
+	secret.StringData = map[string]string{
+		"password":      db.Password,
+		"adminPassword": db.AdminPassword,
+	}
+
+	if err := s.Cache.Update(KafkaConnectorDBSecret, secret); err != nil {
+		return err
+	}
+
+	role := &rbac.Role{}
+	if err := s.Cache.Create(KafkaConnectorDBSecretRole, nn, role); err != nil {
+		return err
+	}
+
+	labeler(role)
+	role.Rules = []rbac.PolicyRule{{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		ResourceNames: []string{nn.Name},
+		Verbs:         []string{"get"},
+	}}
+
+	if err := s.Cache.Update(KafkaConnectorDBSecretRole, role); err != nil {
+		return err
+	}
+
+	rb := &rbac.RoleBinding{}
+	if err := s.Cache.Create(KafkaConnectorDBSecretRoleBinding, nn, rb); err != nil {
+		return err
+	}
+
+	labeler(rb)
+	// strimzi names the service account of a KafkaConnect cluster after the cluster
+	rb.Subjects = []rbac.Subject{{
+		Kind:      "ServiceAccount",
+		Name:      fmt.Sprintf("%s-connect", getConnectClusterName(s.Env)),
+		Namespace: getConnectNamespace(s.Env),
+	}}
+	rb.RoleRef = rbac.RoleRef{
+		APIGroup: "rbac.authorization.k8s.io",
+		Kind:     "Role",
+		Name:     nn.Name,
+	}
+
+	return s.Cache.Update(KafkaConnectorDBSecretRoleBinding, rb)
+}
+
+// getConnectorProblem returns a description of why the connector is not running, and whether it
+// has failed rather than still being started.
+func getConnectorProblem(k *strimziconnect.KafkaConnector) (string, bool) {
+	status := k.Status.ConnectorStatus
+
+	for _, condition := range k.Status.Conditions {
+		if condition.Type == "NotReady" && condition.Status == "True" {
+			return fmt.Sprintf("%s is not ready: %s", k.Name, condition.Message), true
+		}
+	}
+
+	if k.Status.ObservedGeneration < k.Generation || k.Generation == 0 || status == nil {
+		return fmt.Sprintf("%s is pending", k.Name), false
+	}
+
+	if status.Connector.State == "FAILED" {
+		return fmt.Sprintf("%s has failed", k.Name), true
+	}
+
+	for _, task := range status.Tasks {
+		if task.State == "FAILED" {
+			return fmt.Sprintf("%s task %d has failed", k.Name, task.ID), true
+		}
+	}
+
+	if status.Connector.State != "RUNNING" {
+		return fmt.Sprintf("%s is %s", k.Name, strings.ToLower(status.Connector.State)), false
+	}
+
+	for _, task := range status.Tasks {
+		if task.State != "RUNNING" {
+			return fmt.Sprintf("%s task %d is %s", k.Name, task.ID, strings.ToLower(task.State)), false
+		}
+	}
+
+	return "", false
+}
+
+// setConnectorCondition reports the state of the app's connectors, as last observed by the strimzi
+// operator, as a condition on the app.
+func setConnectorCondition(app *crd.ClowdApp, connectors []strimziconnect.KafkaConnector) {
+	condition := crd.ClowdCondition{
+		Type:    crd.KafkaConnectorsReady,
+		Status:  core.ConditionTrue,
+		Reason:  "ConnectorsRunning",
+		Message: "All connectors are running",
+	}
+
+	failed, pending := []string{}, []string{}
+	for i := range connectors {
+		problem, isFailure := getConnectorProblem(&connectors[i])
+		if problem == "" {
+			continue
+		}
+		if isFailure {
+			failed = append(failed, problem)
+		} else {
+			pending = append(pending, problem)
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		condition.Status = core.ConditionFalse
+		condition.Reason = "ConnectorsFailed"
+		condition.Message = strings.Join(append(failed, pending...), "; ")
+	case len(pending) > 0:
+		condition.Status = core.ConditionUnknown
+		condition.Reason = "ConnectorsPending"
+		condition.Message = strings.Join(pending, "; ")
+	}
+
+	crd.UpdateClowdAppCondition(&app.Status, &condition)
+}
+
+// getConnectorRequests returns the names of the connectors declared by the apps in the
+// environment. Apps which are being deleted are not counted.
+func getConnectorRequests(env *crd.ClowdEnvironment, appList *crd.ClowdAppList) map[string]bool {
+	requests := map[string]bool{}
+
+	for i := range appList.Items {
+		app := &appList.Items[i]
+		if app.Spec.EnvName != env.Name || app.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		for _, connector := range app.Spec.KafkaConnectors {
+			requests[getConnectorName(app, connector)] = true
+		}
+	}
+
+	return requests
+}
+
+// gcConnectors deletes the KafkaConnectors created for the environment which are no longer
+// declared by any app.
+func gcConnectors(p *providers.Provider, requests map[string]bool) error {
+	connectorList := strimziconnect.KafkaConnectorList{}
+	err := p.Client.List(
+		p.Ctx,
+		&connectorList,
+		client.InNamespace(getConnectNamespace(p.Env)),
+		client.MatchingLabels{"env": p.Env.Name, "strimzi.io/cluster": getConnectClusterName(p.Env)},
+	)
+	if err != nil {
+		return errors.Wrap("Connector cleanup failed: Error listing connectors", err)
+	}
+
+	for i := range connectorList.Items {
+		connector := &connectorList.Items[i]
+
+		if !isOwnedBy(connector, p.Env) || requests[connector.Name] {
+			continue
+		}
+
+		if err := p.Client.Delete(p.Ctx, connector); err != nil {
+			return errors.Wrap("Connector cleanup failed: Error deleting connector", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package kafka
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	strimziconnect "cloud.redhat.com/clowder/v2/apis/strimzi/v1beta1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRenderConnectorConfig(t *testing.T) {
+	db := &config.DatabaseConfig{
+		Hostname:      "app-db.ns.svc",
+		Port:          5432,
+		Name:          "app",
+		Username:      "user",
+		Password:      "pass",
+		AdminUsername: "postgres",
+		AdminPassword: "admin",
+		SslMode:       "disable",
+	}
+
+	secretNN := types.NamespacedName{Name: "app-connect-db", Namespace: "ns"}
+
+	rendered, err := renderConnectorConfig(map[string]string{
+		"database.hostname":   "${db.hostname}",
+		"database.port":       "${db.port}",
+		"database.dbname":     "${db.name}",
+		"database.user":       "${db.adminUsername}",
+		"database.password":   "${db.adminPassword}",
+		"database.sslmode":    "${db.sslMode}",
+		"database.url":        "postgres://${db.username}:${db.password}@${db.hostname}/${db.name}",
+		"table.include.list":  "public.hosts",
+		"config.providers.ex": "${file:/opt/kafka/external.properties:key}",
+	}, db, secretNN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"database.hostname":   "app-db.ns.svc",
+		"database.port":       "5432",
+		"database.dbname":     "app",
+		"database.user":       "postgres",
+		"database.password":   "${secrets:ns/app-connect-db:adminPassword}",
+		"database.sslmode":    "disable",
+		"database.url":        "postgres://user:${secrets:ns/app-connect-db:password}@app-db.ns.svc/app",
+		"table.include.list":  "public.hosts",
+		"config.providers.ex": "${file:/opt/kafka/external.properties:key}",
+	}
+	for key, value := range expected {
+		if rendered[key] != value {
+			t.Errorf("Wrong value for %s: %s; expected %s", key, rendered[key], value)
+		}
+	}
+
+	if _, err := renderConnectorConfig(map[string]string{"database.hostname": "${db.hostname}"}, nil, secretNN); err == nil {
+		t.Error("Expected an error rendering a database placeholder for an app with no database")
+	}
+
+	if _, err := renderConnectorConfig(map[string]string{"database.host": "${db.host}"}, db, secretNN); err == nil {
+		t.Error("Expected an error rendering an unknown database placeholder")
+	}
+}
+
+func TestConnectorCondition(t *testing.T) {
+	makeConnector := func(name string, state string, taskStates ...string) strimziconnect.KafkaConnector {
+		k := strimziconnect.KafkaConnector{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+			Status: strimziconnect.KafkaConnectorStatus{
+				ObservedGeneration: 1,
+				ConnectorStatus: &strimziconnect.KafkaConnectorConnectorStatus{
+					Connector: strimziconnect.KafkaConnectorState{State: state},
+				},
+			},
+		}
+		for i, taskState := range taskStates {
+			k.Status.ConnectorStatus.Tasks = append(
+				k.Status.ConnectorStatus.Tasks,
+				strimziconnect.KafkaConnectorState{ID: int32(i), State: taskState},
+			)
+		}
+		return k
+	}
+
+	pending := makeConnector("app-pending", "RUNNING", "RUNNING")
+	pending.Generation = 2
+
+	tests := []struct {
+		name       string
+		connectors []strimziconnect.KafkaConnector
+		status     core.ConditionStatus
+		message    string
+	}{{
+		name:       "running",
+		connectors: []strimziconnect.KafkaConnector{makeConnector("app-a", "RUNNING", "RUNNING", "RUNNING")},
+		status:     core.ConditionTrue,
+	}, {
+		name:       "pending",
+		connectors: []strimziconnect.KafkaConnector{makeConnector("app-a", "RUNNING", "RUNNING"), pending},
+		status:     core.ConditionUnknown,
+		message:    "app-pending is pending",
+	}, {
+		name:       "paused",
+		connectors: []strimziconnect.KafkaConnector{makeConnector("app-a", "PAUSED", "PAUSED")},
+		status:     core.ConditionUnknown,
+		message:    "app-a is paused",
+	}, {
+		name:       "failed task",
+		connectors: []strimziconnect.KafkaConnector{pending, makeConnector("app-b", "RUNNING", "RUNNING", "FAILED")},
+		status:     core.ConditionFalse,
+		message:    "app-b task 1 has failed; app-pending is pending",
+	}}
+
+	for _, tt := range tests {
+		app := &crd.ClowdApp{}
+		setConnectorCondition(app, tt.connectors)
+
+		if len(app.Status.Conditions) != 1 {
+			t.Fatalf("%s: wrong number of conditions %d; expected 1", tt.name, len(app.Status.Conditions))
+		}
+		condition := app.Status.Conditions[0]
+		if condition.Status != tt.status {
+			t.Errorf("%s: wrong condition status %s; expected %s", tt.name, condition.Status, tt.status)
+		}
+		if !strings.Contains(condition.Message, tt.message) {
+			t.Errorf("%s: wrong condition message %q; expected %q", tt.name, condition.Message, tt.message)
+		}
+	}
+}
+
+func TestConnectorRequests(t *testing.T) {
+	env := getKafkaTestEnv()
+	now := metav1.Now()
+
+	makeApp := func(name string, connectors ...string) crd.ClowdApp {
+		app := crd.ClowdApp{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec:       crd.ClowdAppSpec{EnvName: "env"},
+		}
+		for _, connector := range connectors {
+			app.Spec.KafkaConnectors = append(app.Spec.KafkaConnectors, crd.KafkaConnectorSpec{Name: connector})
+		}
+		return app
+	}
+
+	deleted := makeApp("deleted", "cdc")
+	deleted.SetDeletionTimestamp(&now)
+	other := makeApp("other", "cdc")
+	other.Spec.EnvName = "other-env"
+
+	requests := getConnectorRequests(&env, &crd.ClowdAppList{Items: []crd.ClowdApp{
+		makeApp("a", "cdc", "sink"),
+		deleted,
+		other,
+	}})
+
+	if len(requests) != 2 || !requests["ns-a-cdc"] || !requests["ns-a-sink"] {
+		t.Errorf("Wrong connector requests %v; expected ns-a-cdc and ns-a-sink", requests)
+	}
+}
+
+func TestGCConnectors(t *testing.T) {
+	env := getKafkaTestEnv()
+	env.UID = types.UID("env-uid")
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := strimziconnect.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	makeConnector := func(name string, owned bool) *strimziconnect.KafkaConnector {
+		connector := &strimziconnect.KafkaConnector{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: getConnectNamespace(&env),
+				Labels:    map[string]string{"env": env.Name, "strimzi.io/cluster": getConnectClusterName(&env)},
+			},
+		}
+		if owned {
+			connector.SetOwnerReferences([]metav1.OwnerReference{env.MakeOwnerReference()})
+		}
+		return connector
+	}
+
+	p := providers.Provider{
+		Ctx: context.TODO(),
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			makeConnector("ns-a-cdc", true),
+			makeConnector("ns-a-removed", true),
+			makeConnector("unmanaged", false),
+		).Build(),
+		Env: &env,
+	}
+
+	if err := gcConnectors(&p, map[string]bool{"ns-a-cdc": true}); err != nil {
+		t.Fatal(err)
+	}
+
+	connectorList := strimziconnect.KafkaConnectorList{}
+	if err := p.Client.List(p.Ctx, &connectorList); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining := map[string]bool{}
+	for _, connector := range connectorList.Items {
+		remaining[connector.Name] = true
+	}
+
+	if len(remaining) != 2 || !remaining["ns-a-cdc"] || !remaining["unmanaged"] {
+		t.Errorf("Wrong connectors after cleanup %v; expected ns-a-cdc and unmanaged", remaining)
+	}
+}
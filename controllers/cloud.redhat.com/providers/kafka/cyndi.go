@@ -10,6 +10,7 @@ import (
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
 	db "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/database"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -47,6 +48,8 @@ func validateCyndiPipeline(
 		}
 	}
 
+	setCyndiPipelineCondition(app, &pipeline)
+
 	return nil
 }
 
@@ -97,7 +100,7 @@ func createCyndiPipeline(
 	pipeline.Spec.InventoryDbSecret = &inventoryDbSecret
 	pipeline.Spec.DbSecret = &appDbSecret
 	pipeline.Spec.ConnectCluster = &connectClusterName
-	pipeline.Spec.InsightsOnly = app.Spec.Cyndi.InsightsOnly
+	setCyndiPipelineOptions(&pipeline.Spec, app.Spec.Cyndi)
 
 	// it would be best for the ClowdApp to own this, but since cross-namespace OwnerReferences
 	// are not permitted, make this owned by the ClowdEnvironment
@@ -107,9 +110,64 @@ func createCyndiPipeline(
 		return err
 	}
 
+	setCyndiPipelineCondition(app, pipeline)
+
 	return nil
 }
 
+// setCyndiPipelineOptions copies the options an app sets in its cyndi stanza onto the pipeline.
+// Options the app leaves unset are left to the cyndi operator's defaults.
+func setCyndiPipelineOptions(spec *cyndi.CyndiPipelineSpec, cyndiSpec crd.CyndiSpec) {
+	spec.InsightsOnly = cyndiSpec.InsightsOnly
+	spec.AdditionalFilters = cyndiSpec.AdditionalFilters
+	spec.ValidationThreshold = cyndiSpec.ValidationThreshold
+	spec.MaxAge = cyndiSpec.MaxAge
+
+	spec.Topic = nil
+	if cyndiSpec.Topic != "" {
+		topic := cyndiSpec.Topic
+		spec.Topic = &topic
+	}
+}
+
+// setCyndiPipelineCondition reports the state of the app's CyndiPipeline, as last observed by the
+// cyndi operator, as a condition on the app.
+func setCyndiPipelineCondition(app *crd.ClowdApp, pipeline *cyndi.CyndiPipeline) {
+	condition := crd.ClowdCondition{
+		Type:   crd.CyndiPipelineValid,
+		Status: core.ConditionUnknown,
+	}
+
+	switch pipeline.GetState() {
+	case cyndi.StateValid:
+		condition.Status = core.ConditionTrue
+		condition.Reason = "PipelineValid"
+		condition.Message = fmt.Sprintf(
+			"%d hosts syndicated into table %s", pipeline.Status.HostCount, pipeline.Status.ActiveTableName,
+		)
+	case cyndi.StateInvalid:
+		condition.Status = core.ConditionFalse
+		condition.Reason = "PipelineInvalid"
+		condition.Message = fmt.Sprintf(
+			"validation of table %s has failed %d times", pipeline.Status.TableName, pipeline.Status.ValidationFailedCount,
+		)
+		if valid := meta.FindStatusCondition(pipeline.Status.Conditions, "Valid"); valid != nil && valid.Message != "" {
+			condition.Message = fmt.Sprintf("%s: %s", condition.Message, valid.Message)
+		}
+	case cyndi.StateInitialSync:
+		condition.Reason = "InitialSync"
+		condition.Message = fmt.Sprintf("initial sync into table %s is in progress", pipeline.Status.TableName)
+	case cyndi.StateNew:
+		condition.Reason = "PipelinePending"
+		condition.Message = "pipeline has not yet been set up by the cyndi operator"
+	default:
+		condition.Reason = "PipelineStateUnknown"
+		condition.Message = fmt.Sprintf("pipeline is in state %s", pipeline.GetState())
+	}
+
+	crd.UpdateClowdAppCondition(&app.Status, &condition)
+}
+
 func getDbSecretInSameEnv(ctx context.Context, cl client.Client, cache *providers.ObjectCache, app *crd.ClowdApp, name string) (*core.Secret, error) {
 	// locate the clowdapp named 'name' in the same env as 'app' and return its DB secret
 	// TODO: switch this to use cache instead of getting secret out of k8s?
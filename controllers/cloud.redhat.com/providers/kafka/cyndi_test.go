@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"strings"
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	cyndi "cloud.redhat.com/clowder/v2/apis/cyndi-operator/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCyndiPipelineOptions(t *testing.T) {
+	threshold := int64(10)
+	spec := cyndi.CyndiPipelineSpec{}
+
+	setCyndiPipelineOptions(&spec, crd.CyndiSpec{
+		InsightsOnly:        true,
+		AdditionalFilters:   []map[string]string{{"type": "com.redhat.cloud.inventory.filter.HostTypeFilter"}},
+		ValidationThreshold: &threshold,
+		Topic:               "platform.inventory.events-env",
+	})
+
+	if !spec.InsightsOnly || len(spec.AdditionalFilters) != 1 {
+		t.Errorf("Wrong pipeline spec %+v", spec)
+	}
+	if spec.ValidationThreshold == nil || *spec.ValidationThreshold != 10 {
+		t.Errorf("Wrong validation threshold %v", spec.ValidationThreshold)
+	}
+	if spec.MaxAge != nil {
+		t.Errorf("Max age set although app left it unset: %d", *spec.MaxAge)
+	}
+	if spec.Topic == nil || *spec.Topic != "platform.inventory.events-env" {
+		t.Errorf("Wrong topic %v", spec.Topic)
+	}
+
+	setCyndiPipelineOptions(&spec, crd.CyndiSpec{})
+	if spec.Topic != nil || spec.ValidationThreshold != nil {
+		t.Errorf("Options not cleared %+v", spec)
+	}
+}
+
+func TestCyndiPipelineCondition(t *testing.T) {
+	makePipeline := func(valid metav1.ConditionStatus, initialSync bool) *cyndi.CyndiPipeline {
+		return &cyndi.CyndiPipeline{
+			Status: cyndi.CyndiPipelineStatus{
+				PipelineVersion:       "1_2",
+				TableName:             "hosts_v1_2",
+				ActiveTableName:       "hosts_v1_1",
+				InitialSyncInProgress: initialSync,
+				ValidationFailedCount: 3,
+				HostCount:             42,
+				Conditions: []metav1.Condition{{
+					Type:    "Valid",
+					Status:  valid,
+					Message: "5% of hosts are missing",
+				}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		pipeline *cyndi.CyndiPipeline
+		status   core.ConditionStatus
+		message  string
+	}{{
+		name:     "new",
+		pipeline: &cyndi.CyndiPipeline{},
+		status:   core.ConditionUnknown,
+		message:  "not yet been set up",
+	}, {
+		name:     "initial sync",
+		pipeline: makePipeline(metav1.ConditionUnknown, true),
+		status:   core.ConditionUnknown,
+		message:  "initial sync into table hosts_v1_2",
+	}, {
+		name:     "valid",
+		pipeline: makePipeline(metav1.ConditionTrue, false),
+		status:   core.ConditionTrue,
+		message:  "42 hosts syndicated into table hosts_v1_1",
+	}, {
+		name:     "invalid",
+		pipeline: makePipeline(metav1.ConditionFalse, false),
+		status:   core.ConditionFalse,
+		message:  "failed 3 times: 5% of hosts are missing",
+	}}
+
+	for _, tt := range tests {
+		app := &crd.ClowdApp{}
+		setCyndiPipelineCondition(app, tt.pipeline)
+
+		if len(app.Status.Conditions) != 1 {
+			t.Fatalf("%s: wrong number of conditions %d; expected 1", tt.name, len(app.Status.Conditions))
+		}
+		condition := app.Status.Conditions[0]
+		if condition.Status != tt.status {
+			t.Errorf("%s: wrong condition status %s; expected %s", tt.name, condition.Status, tt.status)
+		}
+		if !strings.Contains(condition.Message, tt.message) {
+			t.Errorf("%s: wrong condition message %q; expected %q", tt.name, condition.Message, tt.message)
+		}
+	}
+}
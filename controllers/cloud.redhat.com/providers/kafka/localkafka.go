@@ -8,6 +8,7 @@ import (
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1/common"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
 	obj "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/object"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
 	"github.com/segmentio/kafka-go"
@@ -48,24 +49,32 @@ var LocalZookeeperService = providers.NewSingleResourceIdent(ProvName, "local_zo
 var LocalZookeeperPVC = providers.NewSingleResourceIdent(ProvName, "local_zookeeper_pvc", &core.PersistentVolumeClaim{})
 
 func (k *localKafka) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
-	if len(app.Spec.KafkaTopics) == 0 {
+	if len(getRequestedTopics(k.Env, app)) == 0 {
 		return nil
 	}
 
 	host := fmt.Sprintf("%s:29092", k.Config.Brokers[0].Hostname)
+	client := kafka.Client{Addr: kafka.TCP(host)}
 
-	for _, topic := range app.Spec.KafkaTopics {
-		topicName := fmt.Sprintf(
-			"%s-%s-%s", topic.TopicName, k.Env.Name, k.Env.GetClowdNamespace(),
-		)
+	// The derived topics follow the requested ones
+	requested := len(getRequestedTopics(k.Env, app))
 
-		k.Config.Topics = append(
-			k.Config.Topics,
-			config.TopicConfig{
-				Name:          topicName,
-				RequestedName: topic.TopicName,
-			},
-		)
+	for i, topic := range getAppTopics(k.Env, app) {
+		topicName, err := getLocalTopicName(topic.TopicName, k.Env, app.Namespace)
+		if err != nil {
+			return err
+		}
+
+		tc := config.TopicConfig{
+			Name:          topicName,
+			RequestedName: topic.TopicName,
+		}
+		if topic.Access.CanConsume() {
+			group := getConsumerGroup(app)
+			tc.ConsumerGroup = &group
+		}
+
+		k.Config.Topics = append(k.Config.Topics, tc)
 
 		d := time.Now().Add(10 * time.Second)
 		ctx, cancel := context.WithDeadline(k.Ctx, d)
@@ -79,12 +88,33 @@ func (k *localKafka) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
 			return err
 		}
 		defer conn.Close()
+
+		// Topics are auto-created with the broker defaults, so the retention of the derived topics
+		// is set once they exist. The config of the requested topics is left as it always has been.
+		if i < requested {
+			continue
+		}
+
+		err = setLocalKraftTopicConfig(ctx, &client, kafka.TopicConfig{
+			Topic:         topicName,
+			ConfigEntries: getTopicConfigEntries(topic),
+		})
+		if err != nil {
+			return errors.Wrap(fmt.Sprintf("couldn't set config on topic %s", topicName), err)
+		}
 	}
 
 	c.Kafka = &k.Config
 	return nil
 }
 
+func getLocalTopicName(topicName string, env *crd.ClowdEnvironment, namespace string) (string, error) {
+	if name, ok, err := getTemplatedTopicName(topicName, env, namespace); ok || err != nil {
+		return name, err
+	}
+	return fmt.Sprintf("%s-%s-%s", topicName, env.Name, env.GetClowdNamespace()), nil
+}
+
 // NewLocalKafka returns a new local kafka provider object.
 func NewLocalKafka(p *providers.Provider) (providers.ClowderProvider, error) {
 	config := config.KafkaConfig{
@@ -0,0 +1,430 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1/common"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	obj "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/object"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
+	"github.com/segmentio/kafka-go"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// LocalKafkaExternalService identifies the NodePort service exposing the local-kraft kafka
+// external listener
+var LocalKafkaExternalService = providers.NewSingleResourceIdent(ProvName, "local_kafka_external_service", &core.Service{})
+
+// localKraftClusterID is the fixed KRaft cluster ID used to format the local instance's storage.
+const localKraftClusterID = "4L6g3nShT-eMCtK--X86sw"
+
+const localKraftExternalPort = 9094
+
+type localKraftKafka struct {
+	providers.Provider
+	Config config.KafkaConfig
+}
+
+func (k *localKraftKafka) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
+	if len(getRequestedTopics(k.Env, app)) == 0 {
+		return nil
+	}
+
+	host := fmt.Sprintf("%s:%d", k.Config.Brokers[0].Hostname, *k.Config.Brokers[0].Port)
+
+	topics, err := buildLocalKraftTopics(app, k.Env)
+	if err != nil {
+		return err
+	}
+
+	// Bound the admin calls so that an unavailable broker doesn't hold up the reconciliation
+	// of other apps.
+	ctx, cancel := context.WithTimeout(k.Ctx, 10*time.Second)
+	defer cancel()
+
+	if err := createLocalKraftTopics(ctx, host, topics); err != nil {
+		return err
+	}
+
+	for i, topic := range getAppTopics(k.Env, app) {
+		tc := config.TopicConfig{
+			Name:          topics[i].Topic,
+			RequestedName: topic.TopicName,
+		}
+		if topic.Access.CanConsume() {
+			group := getConsumerGroup(app)
+			tc.ConsumerGroup = &group
+		}
+
+		k.Config.Topics = append(k.Config.Topics, tc)
+	}
+
+	c.Kafka = &k.Config
+	return nil
+}
+
+// buildLocalKraftTopics returns the topic configurations to create on the local-kraft broker
+// for the given app. There is only a single broker so the replication factor is always 1.
+func buildLocalKraftTopics(app *crd.ClowdApp, env *crd.ClowdEnvironment) ([]kafka.TopicConfig, error) {
+	topics := []kafka.TopicConfig{}
+
+	for _, topic := range getAppTopics(env, app) {
+		partitions := int(topic.Partitions)
+		if partitions == 0 {
+			partitions = 1
+		}
+
+		name, err := getLocalTopicName(topic.TopicName, env, app.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		topics = append(topics, kafka.TopicConfig{
+			Topic:             name,
+			NumPartitions:     partitions,
+			ReplicationFactor: 1,
+			ConfigEntries:     getTopicConfigEntries(topic),
+		})
+	}
+
+	return topics, nil
+}
+
+// getTopicConfigEntries returns the config requested for a topic, sorted by name.
+func getTopicConfigEntries(topic crd.KafkaTopicSpec) []kafka.ConfigEntry {
+	keys := []string{}
+	for key := range topic.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := []kafka.ConfigEntry{}
+	for _, key := range keys {
+		entries = append(entries, kafka.ConfigEntry{
+			ConfigName:  key,
+			ConfigValue: topic.Config[key],
+		})
+	}
+
+	return entries
+}
+
+// createLocalKraftTopics creates the topics on the local-kraft broker, adds partitions to those
+// which have fewer than requested, and sets the requested config on them. The deadline of the
+// context bounds the connections as well as every request made over them.
+func createLocalKraftTopics(ctx context.Context, host string, topics []kafka.TopicConfig) error {
+	conn, err := kafka.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return errors.Wrap("couldn't connect to local kafka", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return errors.Wrap("couldn't set deadline on local kafka connection", err)
+		}
+	}
+
+	controller, err := conn.Controller()
+	if err != nil {
+		return errors.Wrap("couldn't find local kafka controller", err)
+	}
+
+	controllerHost := net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port))
+
+	controllerConn, err := kafka.DialContext(ctx, "tcp", controllerHost)
+	if err != nil {
+		return errors.Wrap("couldn't connect to local kafka controller", err)
+	}
+	defer controllerConn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := controllerConn.SetDeadline(deadline); err != nil {
+			return errors.Wrap("couldn't set deadline on local kafka controller connection", err)
+		}
+	}
+
+	client := kafka.Client{Addr: kafka.TCP(controllerHost)}
+
+	for _, topic := range topics {
+		// CreateTopics is a no-op for topics that already exist
+		if err := controllerConn.CreateTopics(topic); err != nil {
+			return errors.Wrap(fmt.Sprintf("couldn't create topic %s", topic.Topic), err)
+		}
+
+		if err := setLocalKraftTopicConfig(ctx, &client, topic); err != nil {
+			return errors.Wrap(fmt.Sprintf("couldn't set config on topic %s", topic.Topic), err)
+		}
+
+		partitions, err := controllerConn.ReadPartitions(topic.Topic)
+		if err != nil {
+			return errors.Wrap(fmt.Sprintf("couldn't read partitions for topic %s", topic.Topic), err)
+		}
+
+		if len(partitions) >= topic.NumPartitions {
+			continue
+		}
+
+		resp, err := client.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{
+			Topics: []kafka.TopicPartitionsConfig{{
+				Name:  topic.Topic,
+				Count: int32(topic.NumPartitions),
+			}},
+		})
+		if err != nil {
+			return errors.Wrap(fmt.Sprintf("couldn't add partitions to topic %s", topic.Topic), err)
+		}
+		if resp.Errors[topic.Topic] != nil {
+			return errors.Wrap(fmt.Sprintf("couldn't add partitions to topic %s", topic.Topic), resp.Errors[topic.Topic])
+		}
+	}
+
+	return nil
+}
+
+// setLocalKraftTopicConfig sets the requested config entries on a topic, so that changes to the
+// config of an existing topic are applied. Entries which are no longer requested are left as they
+// are rather than being reset to the broker defaults.
+func setLocalKraftTopicConfig(ctx context.Context, client *kafka.Client, topic kafka.TopicConfig) error {
+	if len(topic.ConfigEntries) == 0 {
+		return nil
+	}
+
+	configs := []kafka.IncrementalAlterConfigsRequestConfig{}
+	for _, entry := range topic.ConfigEntries {
+		configs = append(configs, kafka.IncrementalAlterConfigsRequestConfig{
+			Name:            entry.ConfigName,
+			Value:           entry.ConfigValue,
+			ConfigOperation: kafka.ConfigOperationSet,
+		})
+	}
+
+	resp, err := client.IncrementalAlterConfigs(ctx, &kafka.IncrementalAlterConfigsRequest{
+		Resources: []kafka.IncrementalAlterConfigsRequestResource{{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: topic.Topic,
+			Configs:      configs,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, resource := range resp.Resources {
+		if resource.Error != nil {
+			return resource.Error
+		}
+	}
+
+	return nil
+}
+
+// NewLocalKraftKafka returns a new local-kraft kafka provider object.
+func NewLocalKraftKafka(p *providers.Provider) (providers.ClowderProvider, error) {
+	config := config.KafkaConfig{
+		Topics: []config.TopicConfig{},
+		Brokers: []config.BrokerConfig{{
+			Hostname: fmt.Sprintf("%v-kafka.%v.svc", p.Env.Name, p.Env.GetClowdNamespace()),
+			Port:     utils.IntPtr(29092),
+		}},
+	}
+
+	kafkaProvider := localKraftKafka{
+		Provider: *p,
+		Config:   config,
+	}
+
+	nodePortConfig := p.Env.Spec.Providers.Kafka.LocalNodePort
+
+	kafkaCacheMap := []providers.ResourceIdent{
+		LocalKafkaDeployment,
+		LocalKafkaService,
+	}
+
+	if p.Env.Spec.Providers.Kafka.PVC {
+		kafkaCacheMap = append(kafkaCacheMap, LocalKafkaPVC)
+	}
+
+	makeFn := func(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool) {
+		makeLocalKraftKafka(o, objMap, usePVC, nodePort, nodePortConfig)
+	}
+
+	if err := providers.CachedMakeComponent(p.Cache, kafkaCacheMap, p.Env, "kafka", makeFn, p.Env.Spec.Providers.Kafka.PVC, p.Env.IsNodePort()); err != nil {
+		return &kafkaProvider, err
+	}
+
+	if nodePortConfig.Port != 0 {
+		makeExternalFn := func(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool) {
+			makeLocalKraftExternalService(o, objMap, nodePortConfig)
+		}
+
+		externalCacheMap := []providers.ResourceIdent{LocalKafkaExternalService}
+
+		if err := providers.CachedMakeComponent(p.Cache, externalCacheMap, p.Env, "kafka-external", makeExternalFn, false, true); err != nil {
+			return &kafkaProvider, err
+		}
+	}
+
+	return &kafkaProvider, nil
+}
+
+func getLocalKraftExternalHost(cfg crd.KafkaLocalNodePortConfig) string {
+	if cfg.Host == "" {
+		return "localhost"
+	}
+	return cfg.Host
+}
+
+func makeLocalKraftKafka(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool, external crd.KafkaLocalNodePortConfig) {
+	nn := providers.GetNamespacedName(o, "kafka")
+
+	dd := objMap[LocalKafkaDeployment].(*apps.Deployment)
+	svc := objMap[LocalKafkaService].(*core.Service)
+
+	labels := o.GetLabels()
+	labels["env-app"] = nn.Name
+	labeler := utils.MakeLabeler(nn, labels, o)
+
+	labeler(dd)
+
+	var volSource core.VolumeSource
+	if usePVC {
+		volSource = core.VolumeSource{
+			PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{
+				ClaimName: nn.Name,
+			},
+		}
+	} else {
+		volSource = core.VolumeSource{
+			EmptyDir: &core.EmptyDirVolumeSource{},
+		}
+	}
+
+	dd.Spec.Replicas = common.Int32Ptr(1)
+	dd.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	dd.Spec.Template.Spec.Volumes = []core.Volume{
+		{
+			Name:         nn.Name,
+			VolumeSource: volSource,
+		},
+	}
+	dd.Spec.Template.ObjectMeta.Labels = labels
+
+	listeners := "PLAINTEXT://:29092,CONTROLLER://:29093"
+	advertisedListeners := "PLAINTEXT://" + nn.Name + "." + nn.Namespace + ".svc:29092"
+
+	ports := []core.ContainerPort{
+		{
+			Name:          "kafka",
+			ContainerPort: 29092,
+		},
+		{
+			Name:          "controller",
+			ContainerPort: 29093,
+		},
+	}
+
+	if external.Port != 0 {
+		listeners += fmt.Sprintf(",EXTERNAL://:%d", localKraftExternalPort)
+		advertisedListeners += fmt.Sprintf(",EXTERNAL://%s:%d", getLocalKraftExternalHost(external), external.Port)
+		ports = append(ports, core.ContainerPort{
+			Name:          "external",
+			ContainerPort: localKraftExternalPort,
+		})
+	}
+
+	envVars := makeEnvVars(&[]envVar{
+		{"CLUSTER_ID", localKraftClusterID},
+		{"KAFKA_NODE_ID", "1"},
+		{"KAFKA_PROCESS_ROLES", "broker,controller"},
+		{"KAFKA_LISTENERS", listeners},
+		{"KAFKA_ADVERTISED_LISTENERS", advertisedListeners},
+		{"KAFKA_CONTROLLER_LISTENER_NAMES", "CONTROLLER"},
+		{"KAFKA_CONTROLLER_QUORUM_VOTERS", "1@localhost:29093"},
+		{"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP", "CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT,EXTERNAL:PLAINTEXT"},
+		{"KAFKA_INTER_BROKER_LISTENER_NAME", "PLAINTEXT"},
+		{"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR", "1"},
+		{"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR", "1"},
+		{"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR", "1"},
+		{"KAFKA_AUTO_CREATE_TOPICS_ENABLE", "false"},
+		{"KAFKA_LOG_DIRS", "/var/lib/kafka/data"},
+	})
+
+	probeHandler := core.Handler{
+		TCPSocket: &core.TCPSocketAction{
+			Port: intstr.IntOrString{
+				Type:   intstr.Int,
+				IntVal: 29092,
+			},
+		},
+	}
+
+	livenessProbe := core.Probe{
+		Handler:             probeHandler,
+		InitialDelaySeconds: 10,
+		TimeoutSeconds:      2,
+	}
+	readinessProbe := core.Probe{
+		Handler:             probeHandler,
+		InitialDelaySeconds: 15,
+		TimeoutSeconds:      2,
+	}
+
+	c := core.Container{
+		Name:  nn.Name,
+		Image: "docker.io/apache/kafka:3.7.0",
+		Env:   envVars,
+		Ports: ports,
+		VolumeMounts: []core.VolumeMount{
+			{
+				Name:      nn.Name,
+				MountPath: "/var/lib/kafka/data",
+			},
+		},
+		ReadinessProbe: &readinessProbe,
+		LivenessProbe:  &livenessProbe,
+	}
+
+	dd.Spec.Template.Spec.Containers = []core.Container{c}
+	dd.Spec.Template.SetLabels(labels)
+
+	servicePorts := []core.ServicePort{{Name: "kafka", Port: 29092, Protocol: "TCP"}}
+
+	utils.MakeService(svc, nn, labels, servicePorts, o, nodePort)
+	if usePVC {
+		pvc := objMap[LocalKafkaPVC].(*core.PersistentVolumeClaim)
+		utils.MakePVC(pvc, nn, labels, "1Gi", o)
+	}
+}
+
+func makeLocalKraftExternalService(o obj.ClowdObject, objMap providers.ObjectMap, external crd.KafkaLocalNodePortConfig) {
+	nn := providers.GetNamespacedName(o, "kafka-external")
+
+	svc := objMap[LocalKafkaExternalService].(*core.Service)
+
+	// The service selects the pods of the local kafka deployment
+	labels := o.GetLabels()
+	labels["env-app"] = providers.GetNamespacedName(o, "kafka").Name
+
+	servicePorts := []core.ServicePort{{
+		Name:       "external",
+		Port:       localKraftExternalPort,
+		TargetPort: intstr.FromInt(localKraftExternalPort),
+		NodePort:   external.Port,
+		Protocol:   "TCP",
+	}}
+
+	utils.MakeService(svc, nn, labels, servicePorts, o, true)
+}
@@ -0,0 +1,136 @@
+package kafka
+
+import (
+	"strings"
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getEnvVar(c core.Container, name string) string {
+	for _, ev := range c.Env {
+		if ev.Name == name {
+			return ev.Value
+		}
+	}
+	return ""
+}
+
+func TestLocalKraftKafka(t *testing.T) {
+	env := getKafkaTestEnv()
+
+	dd, svc, pvc := apps.Deployment{}, core.Service{}, core.PersistentVolumeClaim{}
+
+	objMap := providers.ObjectMap{
+		LocalKafkaDeployment: &dd,
+		LocalKafkaService:    &svc,
+		LocalKafkaPVC:        &pvc,
+	}
+
+	makeLocalKraftKafka(&env, objMap, true, false, crd.KafkaLocalNodePortConfig{})
+
+	if dd.Name != "env-kafka" {
+		t.Errorf("Wrong deployment name %s; expected %s", dd.Name, "env-kafka")
+	}
+
+	if pvc.Name != "env-kafka" {
+		t.Errorf("Wrong pvc name %s; expected %s", pvc.Name, "env-kafka")
+	}
+
+	c := dd.Spec.Template.Spec.Containers[0]
+
+	if getEnvVar(c, "KAFKA_PROCESS_ROLES") != "broker,controller" {
+		t.Errorf("Wrong process roles %s", getEnvVar(c, "KAFKA_PROCESS_ROLES"))
+	}
+
+	if getEnvVar(c, "KAFKA_ZOOKEEPER_CONNECT") != "" {
+		t.Error("KRaft kafka should not reference zookeeper")
+	}
+
+	if strings.Contains(getEnvVar(c, "KAFKA_LISTENERS"), "EXTERNAL") {
+		t.Error("External listener configured without a node port")
+	}
+}
+
+func TestLocalKraftKafkaExternalListener(t *testing.T) {
+	env := getKafkaTestEnv()
+
+	dd, svc, extSvc := apps.Deployment{}, core.Service{}, core.Service{}
+
+	objMap := providers.ObjectMap{
+		LocalKafkaDeployment: &dd,
+		LocalKafkaService:    &svc,
+	}
+
+	nodePort := crd.KafkaLocalNodePortConfig{Port: 30092}
+
+	makeLocalKraftKafka(&env, objMap, false, false, nodePort)
+
+	c := dd.Spec.Template.Spec.Containers[0]
+
+	expected := "PLAINTEXT://env-kafka..svc:29092,EXTERNAL://localhost:30092"
+	if getEnvVar(c, "KAFKA_ADVERTISED_LISTENERS") != expected {
+		t.Errorf("Wrong advertised listeners %s; expected %s", getEnvVar(c, "KAFKA_ADVERTISED_LISTENERS"), expected)
+	}
+
+	makeLocalKraftExternalService(&env, providers.ObjectMap{LocalKafkaExternalService: &extSvc}, nodePort)
+
+	if extSvc.Name != "env-kafka-external" {
+		t.Errorf("Wrong service name %s; expected %s", extSvc.Name, "env-kafka-external")
+	}
+
+	if extSvc.Spec.Type != "NodePort" || extSvc.Spec.Ports[0].NodePort != 30092 {
+		t.Errorf("External service not exposed on node port 30092")
+	}
+
+	if extSvc.Spec.Selector["env-app"] != "env-kafka" {
+		t.Errorf("Wrong selector %s; expected %s", extSvc.Spec.Selector["env-app"], "env-kafka")
+	}
+}
+
+func TestBuildLocalKraftTopics(t *testing.T) {
+	env := getKafkaTestEnv()
+
+	app := crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns"},
+		Spec: crd.ClowdAppSpec{
+			KafkaTopics: []crd.KafkaTopicSpec{
+				{TopicName: "default"},
+				{
+					TopicName:  "configured",
+					Partitions: 4,
+					Config: strimzi.KafkaTopicSpecConfig{
+						"retention.ms":   "1000",
+						"cleanup.policy": "compact",
+					},
+				},
+			},
+		},
+	}
+
+	topics, err := buildLocalKraftTopics(&app, &env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(topics) != 2 {
+		t.Fatalf("Wrong number of topics %d; expected 2", len(topics))
+	}
+
+	if topics[0].Topic != "default-env-" || topics[0].NumPartitions != 1 || topics[0].ReplicationFactor != 1 {
+		t.Errorf("Wrong default topic %+v", topics[0])
+	}
+
+	if topics[1].NumPartitions != 4 {
+		t.Errorf("Wrong partitions %d; expected 4", topics[1].NumPartitions)
+	}
+
+	if len(topics[1].ConfigEntries) != 2 || topics[1].ConfigEntries[0].ConfigName != "cleanup.policy" {
+		t.Errorf("Wrong config entries %+v", topics[1].ConfigEntries)
+	}
+}
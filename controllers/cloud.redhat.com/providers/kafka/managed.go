@@ -1,7 +1,10 @@
 package kafka
 
 import (
+	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
@@ -12,6 +15,14 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// managedSaslMechanisms are the SASL mechanisms which may be set in the managed Kafka secret.
+var managedSaslMechanisms = map[string]bool{
+	"PLAIN":         true,
+	"SCRAM-SHA-256": true,
+	"SCRAM-SHA-512": true,
+	"OAUTHBEARER":   true,
+}
+
 type managedKafkaProvider struct {
 	providers.Provider
 }
@@ -40,41 +51,155 @@ func (k *managedKafkaProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) e
 		return err
 	}
 
-	var port int
-	var err error
-
-	if port, err = strconv.Atoi(string(s.Data["port"])); err != nil {
-		return err
+	kafkaConfig, err := buildManagedKafkaConfig(s.Data, getRequestedTopics(k.Env, app), k.Env, app.Namespace)
+	if err != nil {
+		return errors.Wrap("invalid managed Kafka secret", err)
 	}
 
-	kafkaConfig := config.KafkaConfig{}
+	c.Kafka = kafkaConfig
+
+	return nil
+}
+
+// buildManagedKafkaConfig creates the app's kafka config from the data in the managed Kafka secret.
+// The brokers are read from either the comma separated host:port list in "bootstrapServers" or
+// the single "hostname" and "port".
+func buildManagedKafkaConfig(data map[string][]byte, topics []crd.KafkaTopicSpec, env *crd.ClowdEnvironment, namespace string) (*config.KafkaConfig, error) {
+	brokers, err := getManagedBrokers(data)
+	if err != nil {
+		return nil, err
+	}
 
-	password := string(s.Data["password"])
-	username := string(s.Data["username"])
+	var authType *config.BrokerConfigAuthtype
+	var sasl *config.KafkaSASLConfig
 
-	broker := config.BrokerConfig{
-		Hostname: string(s.Data["hostname"]),
-		Port:     &port,
-		Sasl: &config.KafkaSASLConfig{
+	if username, ok := data["username"]; ok {
+		password := string(data["password"])
+		stringUsername := string(username)
+		sasl = &config.KafkaSASLConfig{
 			Password: &password,
-			Username: &username,
-		},
+			Username: &stringUsername,
+		}
+
+		if mechanism, ok := data["saslMechanism"]; ok {
+			stringMechanism := strings.ToUpper(string(mechanism))
+			if !managedSaslMechanisms[stringMechanism] {
+				return nil, errors.New(fmt.Sprintf("unsupported SASL mechanism %s", mechanism))
+			}
+			sasl.SaslMechanism = &stringMechanism
+		}
+
+		saslType := config.BrokerConfigAuthtypeSasl
+		authType = &saslType
+	}
+
+	var caCert *string
+	if ca, ok := data["cacert"]; ok {
+		stringCa := string(ca)
+		caCert = &stringCa
+	}
+
+	for i := range brokers {
+		brokers[i].Authtype = authType
+		brokers[i].Cacert = caCert
+		brokers[i].Sasl = sasl
+	}
+
+	topicNamer, err := getManagedTopicNamer(data, env, namespace)
+	if err != nil {
+		return nil, err
 	}
 
-	kafkaConfig.Brokers = []config.BrokerConfig{broker}
+	kafkaConfig := &config.KafkaConfig{
+		Brokers: brokers,
+		Topics:  []config.TopicConfig{},
+	}
 
-	for _, topic := range app.Spec.KafkaTopics {
+	for _, topic := range topics {
+		name, err := topicNamer(topic.TopicName)
+		if err != nil {
+			return nil, err
+		}
 
 		kafkaConfig.Topics = append(
 			kafkaConfig.Topics,
 			config.TopicConfig{
-				Name:          topic.TopicName,
+				Name:          name,
 				RequestedName: topic.TopicName,
 			},
 		)
 	}
 
-	c.Kafka = &kafkaConfig
+	return kafkaConfig, nil
+}
 
-	return nil
+func getManagedBrokers(data map[string][]byte) ([]config.BrokerConfig, error) {
+	bootstrapServers, ok := data["bootstrapServers"]
+	if !ok {
+		port, err := strconv.Atoi(string(data["port"]))
+		if err != nil {
+			return nil, err
+		}
+		return []config.BrokerConfig{{
+			Hostname: string(data["hostname"]),
+			Port:     &port,
+		}}, nil
+	}
+
+	brokers := []config.BrokerConfig{}
+	for _, server := range strings.Split(string(bootstrapServers), ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+
+		sep := strings.LastIndex(server, ":")
+		if sep == -1 {
+			return nil, errors.New(fmt.Sprintf("bootstrap server %s has no port", server))
+		}
+
+		port, err := strconv.Atoi(server[sep+1:])
+		if err != nil {
+			return nil, errors.Wrap(fmt.Sprintf("bootstrap server %s has an invalid port", server), err)
+		}
+
+		brokers = append(brokers, config.BrokerConfig{
+			Hostname: server[:sep],
+			Port:     &port,
+		})
+	}
+
+	if len(brokers) == 0 {
+		return nil, errors.New("no bootstrap servers defined")
+	}
+
+	return brokers, nil
+}
+
+// getManagedTopicNamer returns a function giving the actual name of a requested topic. A topic
+// listed in the JSON object in "topicMapping" is given the mapped name, any other topic is named by
+// the environment's topic naming template, if set, and given the "topicPrefix", if set.
+func getManagedTopicNamer(data map[string][]byte, env *crd.ClowdEnvironment, namespace string) (func(string) (string, error), error) {
+	mapping := map[string]string{}
+	if rawMapping, ok := data["topicMapping"]; ok {
+		if err := json.Unmarshal(rawMapping, &mapping); err != nil {
+			return nil, errors.Wrap("could not parse topicMapping", err)
+		}
+	}
+
+	prefix := string(data["topicPrefix"])
+
+	return func(requestedName string) (string, error) {
+		if name, ok := mapping[requestedName]; ok {
+			return name, nil
+		}
+		name, ok, err := getTemplatedTopicName(requestedName, env, namespace)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return prefix + name, nil
+		}
+		return prefix + requestedName, nil
+	}, nil
 }
@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagedKafkaConfig(t *testing.T) {
+	topics := []crd.KafkaTopicSpec{{TopicName: "topicOne"}, {TopicName: "topicTwo"}}
+	env := getKafkaTestEnv()
+
+	t.Run("single broker", func(t *testing.T) {
+		kafkaConfig, err := buildManagedKafkaConfig(map[string][]byte{
+			"hostname": []byte("kafka-host-name"),
+			"port":     []byte("27015"),
+			"username": []byte("kafka-username"),
+			"password": []byte("kafka-password"),
+		}, topics, &env, "ns")
+		assert.NoError(t, err)
+
+		assert.Len(t, kafkaConfig.Brokers, 1)
+		broker := kafkaConfig.Brokers[0]
+		assert.Equal(t, "kafka-host-name", broker.Hostname)
+		assert.Equal(t, 27015, *broker.Port)
+		assert.Equal(t, config.BrokerConfigAuthtypeSasl, *broker.Authtype)
+		assert.Equal(t, "kafka-username", *broker.Sasl.Username)
+		assert.Equal(t, "kafka-password", *broker.Sasl.Password)
+		assert.Nil(t, broker.Sasl.SaslMechanism)
+		assert.Nil(t, broker.Cacert)
+
+		assert.Equal(t, []config.TopicConfig{
+			{Name: "topicOne", RequestedName: "topicOne"},
+			{Name: "topicTwo", RequestedName: "topicTwo"},
+		}, kafkaConfig.Topics)
+	})
+
+	t.Run("bootstrap list", func(t *testing.T) {
+		kafkaConfig, err := buildManagedKafkaConfig(map[string][]byte{
+			"bootstrapServers": []byte("broker-0.example.com:9096, broker-1.example.com:9096"),
+			"username":         []byte("kafka-username"),
+			"password":         []byte("kafka-password"),
+			"saslMechanism":    []byte("scram-sha-512"),
+			"cacert":           []byte("-----BEGIN CERTIFICATE-----"),
+			"topicPrefix":      []byte("tenant-"),
+			"topicMapping":     []byte(`{"topicTwo": "shared.topic-two"}`),
+		}, topics, &env, "ns")
+		assert.NoError(t, err)
+
+		assert.Len(t, kafkaConfig.Brokers, 2)
+		for i, hostname := range []string{"broker-0.example.com", "broker-1.example.com"} {
+			broker := kafkaConfig.Brokers[i]
+			assert.Equal(t, hostname, broker.Hostname)
+			assert.Equal(t, 9096, *broker.Port)
+			assert.Equal(t, "SCRAM-SHA-512", *broker.Sasl.SaslMechanism)
+			assert.Equal(t, "-----BEGIN CERTIFICATE-----", *broker.Cacert)
+		}
+
+		assert.Equal(t, []config.TopicConfig{
+			{Name: "tenant-topicOne", RequestedName: "topicOne"},
+			{Name: "shared.topic-two", RequestedName: "topicTwo"},
+		}, kafkaConfig.Topics)
+	})
+
+	t.Run("no sasl", func(t *testing.T) {
+		kafkaConfig, err := buildManagedKafkaConfig(map[string][]byte{
+			"bootstrapServers": []byte("broker-0.example.com:9092"),
+		}, nil, &env, "ns")
+		assert.NoError(t, err)
+		assert.Nil(t, kafkaConfig.Brokers[0].Authtype)
+		assert.Nil(t, kafkaConfig.Brokers[0].Sasl)
+		assert.Equal(t, []config.TopicConfig{}, kafkaConfig.Topics)
+	})
+
+	t.Run("naming template", func(t *testing.T) {
+		templateEnv := getKafkaTestEnv()
+		templateEnv.Spec.Providers.Kafka.TopicNamingTemplate = "{{.Env}}.{{.Topic}}"
+
+		kafkaConfig, err := buildManagedKafkaConfig(map[string][]byte{
+			"bootstrapServers": []byte("broker-0.example.com:9092"),
+			"topicPrefix":      []byte("tenant-"),
+			"topicMapping":     []byte(`{"topicTwo": "shared.topic-two"}`),
+		}, topics, &templateEnv, "ns")
+		assert.NoError(t, err)
+
+		assert.Equal(t, []config.TopicConfig{
+			{Name: "tenant-env.topicOne", RequestedName: "topicOne"},
+			{Name: "shared.topic-two", RequestedName: "topicTwo"},
+		}, kafkaConfig.Topics)
+	})
+
+	invalid := map[string]map[string][]byte{
+		"missing port":   {"bootstrapServers": []byte("broker-0.example.com")},
+		"empty list":     {"bootstrapServers": []byte(" , ")},
+		"bad mechanism":  {"bootstrapServers": []byte("broker:9092"), "username": []byte("u"), "saslMechanism": []byte("GSSAPI")},
+		"bad mapping":    {"bootstrapServers": []byte("broker:9092"), "topicMapping": []byte("topicOne=other")},
+		"no broker port": {"hostname": []byte("broker")},
+	}
+	for name, data := range invalid {
+		if _, err := buildManagedKafkaConfig(data, topics, &env, "ns"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
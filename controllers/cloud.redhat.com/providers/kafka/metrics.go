@@ -177,3 +177,51 @@ var metricsData = []byte(`{
       ]
     }
   }`)
+
+var connectMetricsData = []byte(`{
+    "lowercaseOutputName": true,
+    "lowercaseOutputLabelNames": true,
+    "rules": [
+      {
+        "name": "kafka_connect_worker_$1",
+        "pattern": "kafka.connect<type=connect-worker-metrics><>([a-z-]+)",
+        "type": "GAUGE"
+      },
+      {
+        "labels": {
+          "connector": "$1"
+        },
+        "name": "kafka_connect_worker_connector_$2",
+        "pattern": "kafka.connect<type=connect-worker-metrics, connector=(.+)><>([a-z-]+)",
+        "type": "GAUGE"
+      },
+      {
+        "labels": {
+          "connector": "$1",
+          "status": "$3",
+          "task": "$2"
+        },
+        "name": "kafka_connect_connector_status",
+        "pattern": "kafka.connect<type=connector-task-metrics, connector=(.+), task=(.+)><>status: ([a-z-]+)",
+        "type": "GAUGE",
+        "value": 1
+      },
+      {
+        "labels": {
+          "connector": "$2",
+          "task": "$3"
+        },
+        "name": "kafka_connect_$1_$4",
+        "pattern": "kafka.connect<type=(.+)-metrics, connector=(.+), task=(.+)><>([a-z-]+)",
+        "type": "GAUGE"
+      },
+      {
+        "labels": {
+          "client": "$1"
+        },
+        "name": "kafka_connect_$2",
+        "pattern": "kafka.connect<type=connect-metrics, client-id=(.+)><>([a-z-]+)",
+        "type": "GAUGE"
+      }
+    ]
+  }`)
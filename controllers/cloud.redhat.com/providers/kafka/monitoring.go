@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"fmt"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
+	prom "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+
+	core "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// KafkaConnectMetricsConfigMap is the resource ident for the default KafkaConnect metrics ConfigMap.
+var KafkaConnectMetricsConfigMap = providers.NewSingleResourceIdent(ProvName, "kafka_connect_metrics_config_map", &core.ConfigMap{})
+
+// KafkaPodMonitor is the resource ident for the PodMonitor scraping the Kafka pods.
+var KafkaPodMonitor = providers.NewSingleResourceIdent(ProvName, "kafka_pod_monitor", &prom.PodMonitor{})
+
+// KafkaConnectPodMonitor is the resource ident for the PodMonitor scraping the KafkaConnect pods.
+var KafkaConnectPodMonitor = providers.NewSingleResourceIdent(ProvName, "kafka_connect_pod_monitor", &prom.PodMonitor{})
+
+// KafkaPrometheusRoleBinding is the resource ident for the RoleBindings allowing the environment's
+// Prometheus to scrape the Kafka and KafkaConnect pods.
+var KafkaPrometheusRoleBinding = providers.NewMultiResourceIdent(ProvName, "kafka_prometheus_role_binding", &rbac.RoleBinding{})
+
+// defaultMetricsKey is the ConfigMap key holding the JMX exporter configuration.
+const defaultMetricsKey = "metrics"
+
+// strimziMetricsPort is the name of the port Strimzi exposes JMX exporter metrics on.
+const strimziMetricsPort = "tcp-prometheus"
+
+// getMetricsConfigMapKeyRef returns the name and key of the ConfigMap holding a cluster's JMX
+// exporter configuration. If the environment references its own ConfigMap it must exist in the
+// cluster's namespace, otherwise the ConfigMap created by Clowder is used.
+func (s *strimziProvider) getMetricsConfigMapKeyRef(ref *crd.KafkaMetricsConfigMapRef, namespace string, defaultName string) (string, string, error) {
+	if ref == nil {
+		return defaultName, defaultMetricsKey, nil
+	}
+
+	key := ref.Key
+	if key == "" {
+		key = defaultMetricsKey
+	}
+
+	cm := &core.ConfigMap{}
+	nn := types.NamespacedName{Name: ref.Name, Namespace: namespace}
+
+	if err := s.Client.Get(s.Ctx, nn, cm); err != nil {
+		return "", "", &errors.MissingDependencies{
+			MissingDeps: map[string][]string{"configmaps": {fmt.Sprintf("%s:%s", namespace, ref.Name)}},
+		}
+	}
+
+	if _, ok := cm.Data[key]; !ok {
+		return "", "", errors.New(fmt.Sprintf("metrics configmap %s has no key %s", ref.Name, key))
+	}
+
+	return ref.Name, key, nil
+}
+
+func (s *strimziProvider) createKafkaConnectMetricsConfigMap() (types.NamespacedName, error) {
+	cm := &core.ConfigMap{}
+	nn := types.NamespacedName{
+		Namespace: getConnectNamespace(s.Env),
+		Name:      fmt.Sprintf("%s-metrics", getConnectClusterName(s.Env)),
+	}
+
+	if err := s.Cache.Create(KafkaConnectMetricsConfigMap, nn, cm); err != nil {
+		return types.NamespacedName{}, err
+	}
+
+	cm.Data = map[string]string{defaultMetricsKey: string(connectMetricsData)}
+
+	cm.SetName(nn.Name)
+	cm.SetNamespace(nn.Namespace)
+	cm.SetLabels(providers.Labels{"env": s.Env.Name})
+	cm.SetOwnerReferences([]metav1.OwnerReference{s.Env.MakeOwnerReference()})
+
+	if err := s.Cache.Update(KafkaConnectMetricsConfigMap, cm); err != nil {
+		return types.NamespacedName{}, err
+	}
+
+	return nn, nil
+}
+
+// createPodMonitors creates PodMonitors for the Kafka and KafkaConnect pods, labeled to be picked
+// up by the environment's Prometheus, which is given access to the pods' namespaces.
+func (s *strimziProvider) createPodMonitors() error {
+	monitors := []struct {
+		ident     providers.ResourceIdentSingle
+		name      string
+		namespace string
+		kind      string
+	}{
+		{KafkaPodMonitor, getKafkaName(s.Env), getKafkaNamespace(s.Env), "Kafka"},
+		{KafkaConnectPodMonitor, getConnectClusterName(s.Env), getConnectNamespace(s.Env), "KafkaConnect"},
+	}
+
+	namespaces := map[string]bool{}
+
+	for _, monitor := range monitors {
+		pm := &prom.PodMonitor{}
+		nn := types.NamespacedName{
+			Name:      monitor.name,
+			Namespace: monitor.namespace,
+		}
+
+		if err := s.Cache.Create(monitor.ident, nn, pm); err != nil {
+			return err
+		}
+
+		pm.Spec = makePodMonitorSpec(monitor.name, monitor.kind, monitor.namespace)
+
+		labeler := utils.GetCustomLabeler(map[string]string{"prometheus": s.Env.Name}, nn, s.Env)
+		labeler(pm)
+
+		if err := s.Cache.Update(monitor.ident, pm); err != nil {
+			return err
+		}
+
+		if namespaces[monitor.namespace] {
+			continue
+		}
+		namespaces[monitor.namespace] = true
+
+		if err := s.createPrometheusRoleBinding(monitor.namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func makePodMonitorSpec(clusterName string, kind string, namespace string) prom.PodMonitorSpec {
+	return prom.PodMonitorSpec{
+		PodMetricsEndpoints: []prom.PodMetricsEndpoint{{
+			Interval: "15s",
+			Path:     "/metrics",
+			Port:     strimziMetricsPort,
+		}},
+		NamespaceSelector: prom.NamespaceSelector{
+			MatchNames: []string{namespace},
+		},
+		Selector: metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"strimzi.io/cluster": clusterName,
+				"strimzi.io/kind":    kind,
+			},
+		},
+	}
+}
+
+func (s *strimziProvider) createPrometheusRoleBinding(namespace string) error {
+	rb := &rbac.RoleBinding{}
+	nn := types.NamespacedName{
+		Name:      fmt.Sprintf("%s-kafka-prometheus", s.Env.Name),
+		Namespace: namespace,
+	}
+
+	if err := s.Cache.Create(KafkaPrometheusRoleBinding, nn, rb); err != nil {
+		return err
+	}
+
+	rb.RoleRef = rbac.RoleRef{
+		APIGroup: "rbac.authorization.k8s.io",
+		Kind:     "ClusterRole",
+		Name:     "clowder-prometheus",
+	}
+
+	rb.Subjects = []rbac.Subject{{
+		Kind:      rbac.ServiceAccountKind,
+		Name:      "prometheus",
+		Namespace: s.Env.GetClowdNamespace(),
+	}}
+
+	labeler := utils.GetCustomLabeler(map[string]string{}, nn, s.Env)
+	labeler(rb)
+
+	return s.Cache.Update(KafkaPrometheusRoleBinding, rb)
+}
@@ -0,0 +1,179 @@
+package kafka
+
+import (
+	"fmt"
+	"path"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	cronjobProvider "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/cronjob"
+	deployProvider "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/deployment"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
+
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1beta1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// KafkaUserCertSecret is the resource ident for the copy of an app's KafkaUser client
+// certificate placed in the app's namespace.
+var KafkaUserCertSecret = providers.NewSingleResourceIdent(ProvName, "kafka_user_cert_secret", &core.Secret{})
+
+const kafkaUserCertVolume = "kafka-user-cert"
+
+const kafkaUserCertMountPath = "/etc/kafka/user/"
+
+// useMtls returns whether apps authenticate to the env's Strimzi cluster with client certificates.
+func useMtls(env *crd.ClowdEnvironment) bool {
+	return !env.Spec.Providers.Kafka.EnableLegacyStrimzi && env.Spec.Providers.Kafka.Cluster.Authentication == "tls"
+}
+
+func getKafkaUserAuthenticationType(env *crd.ClowdEnvironment) strimzi.KafkaUserSpecAuthenticationType {
+	if useMtls(env) {
+		return strimzi.KafkaUserSpecAuthenticationTypeTls
+	}
+	return strimzi.KafkaUserSpecAuthenticationTypeScramSha512
+}
+
+func getKafkaUserCertSecretName(app *crd.ClowdApp) string {
+	return fmt.Sprintf("%s-kafka-user", app.Name)
+}
+
+// setBrokerCertificates copies the client certificate that Strimzi generated for the app's
+// KafkaUser into the app's namespace and points the mtls brokers at the mounted files.
+func (s *strimziProvider) setBrokerCertificates(app *crd.ClowdApp) error {
+	ku := &strimzi.KafkaUser{}
+	nn := types.NamespacedName{
+		Name:      getKafkaUsername(s.Env, app),
+		Namespace: getKafkaNamespace(s.Env),
+	}
+
+	if err := s.Client.Get(s.Ctx, nn, ku); err != nil {
+		return err
+	}
+
+	if ku.Status == nil || ku.Status.Secret == nil {
+		return errors.New("no secret in kafkauser status")
+	}
+
+	userSecret := &core.Secret{}
+	secnn := types.NamespacedName{
+		Name:      *ku.Status.Secret,
+		Namespace: getKafkaNamespace(s.Env),
+	}
+
+	if err := s.Client.Get(s.Ctx, secnn, userSecret); err != nil {
+		return err
+	}
+
+	for _, key := range []string{"user.crt", "user.key"} {
+		if userSecret.Data[key] == nil {
+			return errors.New(fmt.Sprintf("no %s in kafkauser secret", key))
+		}
+	}
+
+	appnn := types.NamespacedName{
+		Name:      getKafkaUserCertSecretName(app),
+		Namespace: app.Namespace,
+	}
+
+	secret := &core.Secret{}
+	if err := s.Cache.Create(KafkaUserCertSecret, appnn, secret); err != nil {
+		return err
+	}
+
+	labeler := utils.GetCustomLabeler(nil, appnn, app)
+	labeler(secret)
+
+	secret.Data = map[string][]byte{
+		"user.crt": userSecret.Data["user.crt"],
+		"user.key": userSecret.Data["user.key"],
+	}
+	secret.Type = core.SecretTypeOpaque
+
+	if err := s.Cache.Update(KafkaUserCertSecret, secret); err != nil {
+		return err
+	}
+
+	for i := range s.Config.Brokers {
+		broker := &s.Config.Brokers[i]
+		if broker.Authtype == nil || *broker.Authtype != config.BrokerConfigAuthtypeMtls {
+			continue
+		}
+		broker.Mtls = &config.KafkaMTLSConfig{
+			CertPath: path.Join(kafkaUserCertMountPath, "user.crt"),
+			KeyPath:  path.Join(kafkaUserCertMountPath, "user.key"),
+		}
+	}
+
+	return mountKafkaUserCert(s.Cache, app)
+}
+
+// mountKafkaUserCert mounts the app's client certificate secret into each of the app's
+// deployments and cronjobs.
+func mountKafkaUserCert(cache *providers.ObjectCache, app *crd.ClowdApp) error {
+	dList := apps.DeploymentList{}
+	if err := cache.List(deployProvider.CoreDeployment, &dList); err != nil {
+		return err
+	}
+
+	for _, deployment := range dList.Items {
+		d := deployment
+		addKafkaUserCertVolume(&d.Spec.Template.Spec, getKafkaUserCertSecretName(app))
+
+		if err := cache.Update(deployProvider.CoreDeployment, &d); err != nil {
+			return err
+		}
+	}
+
+	cjList := batch.CronJobList{}
+	if err := cache.List(cronjobProvider.CoreCronJob, &cjList); err != nil {
+		return err
+	}
+
+	for _, cronjob := range cjList.Items {
+		cj := cronjob
+		addKafkaUserCertVolume(&cj.Spec.JobTemplate.Spec.Template.Spec, getKafkaUserCertSecretName(app))
+
+		if err := cache.Update(cronjobProvider.CoreCronJob, &cj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MountKafkaUserCert mounts the app's client certificate secret into the pod spec of a job run for
+// the app, when the app authenticates to the environment's Strimzi cluster with it. Jobs are
+// created outside of the app's reconciliation, so the kafka provider cannot mount it itself.
+func MountKafkaUserCert(env *crd.ClowdEnvironment, app *crd.ClowdApp, spec *core.PodSpec) {
+	if env.Spec.Providers.Kafka.Mode != "operator" || !useMtls(env) || len(getRequestedTopics(env, app)) == 0 {
+		return
+	}
+
+	addKafkaUserCertVolume(spec, getKafkaUserCertSecretName(app))
+}
+
+func addKafkaUserCertVolume(spec *core.PodSpec, secretName string) {
+	spec.Volumes = append(spec.Volumes, core.Volume{
+		Name: kafkaUserCertVolume,
+		VolumeSource: core.VolumeSource{
+			Secret: &core.SecretVolumeSource{
+				SecretName: secretName,
+			},
+		},
+	})
+
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.VolumeMounts = append(c.VolumeMounts, core.VolumeMount{
+			Name:      kafkaUserCertVolume,
+			MountPath: kafkaUserCertMountPath,
+			ReadOnly:  true,
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+)
+
+func TestUseMtls(t *testing.T) {
+	env := getKafkaTestEnv()
+
+	if useMtls(&env) {
+		t.Error("mtls should not be used by default")
+	}
+
+	env.Spec.Providers.Kafka.Cluster.Authentication = "tls"
+	if !useMtls(&env) {
+		t.Error("mtls should be used when cluster authentication is tls")
+	}
+	if getKafkaUserAuthenticationType(&env) != strimzi.KafkaUserSpecAuthenticationTypeTls {
+		t.Error("KafkaUser should use tls authentication")
+	}
+
+	env.Spec.Providers.Kafka.EnableLegacyStrimzi = true
+	if useMtls(&env) {
+		t.Error("mtls should not be used with legacy strimzi")
+	}
+}
+
+func TestBuildTlsBrokerConfigMtls(t *testing.T) {
+	host, port, listenerType := "kafka-bootstrap", int32(9093), "tls"
+	listener := strimzi.KafkaStatusListenersElem{
+		Type: &listenerType,
+		Addresses: []strimzi.KafkaStatusListenersElemAddressesElem{{
+			Host: &host,
+			Port: &port,
+		}},
+	}
+
+	bc := buildTlsBrokerConfig(listener, "cacert", true)
+
+	if *bc.Authtype != config.BrokerConfigAuthtypeMtls {
+		t.Errorf("Wrong authtype %s; expected %s", *bc.Authtype, config.BrokerConfigAuthtypeMtls)
+	}
+	if bc.Sasl != nil {
+		t.Error("mtls broker should not have sasl config")
+	}
+
+	bc = buildTlsBrokerConfig(listener, "cacert", false)
+
+	if *bc.Authtype != config.BrokerConfigAuthtypeSasl || bc.Sasl == nil {
+		t.Error("Expected sasl broker config")
+	}
+}
+
+func TestAddKafkaUserCertVolume(t *testing.T) {
+	d := apps.Deployment{}
+	d.Spec.Template.Spec.Containers = []core.Container{{Name: "app"}}
+
+	addKafkaUserCertVolume(&d.Spec.Template.Spec, "app-kafka-user")
+
+	vols := d.Spec.Template.Spec.Volumes
+	if len(vols) != 1 || vols[0].Secret.SecretName != "app-kafka-user" {
+		t.Fatalf("Wrong volumes %+v", vols)
+	}
+
+	mounts := d.Spec.Template.Spec.Containers[0].VolumeMounts
+	if len(mounts) != 1 || mounts[0].MountPath != kafkaUserCertMountPath {
+		t.Errorf("Wrong volume mounts %+v", mounts)
+	}
+}
+
+func TestMountKafkaUserCertJob(t *testing.T) {
+	env := getKafkaTestEnv()
+	env.Spec.Providers.Kafka.Mode = "operator"
+	env.Spec.Providers.Kafka.Cluster.Authentication = "tls"
+
+	app := crd.ClowdApp{}
+	app.Name = "app"
+
+	spec := core.PodSpec{Containers: []core.Container{{Name: "job"}}}
+
+	MountKafkaUserCert(&env, &app, &spec)
+	if len(spec.Volumes) != 0 {
+		t.Fatalf("Cert was mounted for an app without topics")
+	}
+
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{{TopicName: "topic"}}
+
+	MountKafkaUserCert(&env, &app, &spec)
+	if len(spec.Volumes) != 1 || spec.Volumes[0].Secret.SecretName != "app-kafka-user" {
+		t.Fatalf("Wrong volumes %+v", spec.Volumes)
+	}
+	if len(spec.Containers[0].VolumeMounts) != 1 {
+		t.Errorf("Wrong volume mounts %+v", spec.Containers[0].VolumeMounts)
+	}
+}
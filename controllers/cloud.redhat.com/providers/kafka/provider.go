@@ -26,12 +26,17 @@ var CyndiHostInventoryAppSecret = providers.NewSingleResourceIdent(ProvName, "cy
 // GetKafka returns the correct kafka provider based on the environment.
 func GetKafka(c *providers.Provider) (providers.ClowderProvider, error) {
 	c.Env.ConvertDeprecatedKafkaSpec()
+	if err := validateTopicNamingTemplate(c.Env); err != nil {
+		return nil, err
+	}
 	kafkaMode := c.Env.Spec.Providers.Kafka.Mode
 	switch kafkaMode {
 	case "operator":
 		return NewStrimzi(c)
 	case "local":
 		return NewLocalKafka(c)
+	case "local-kraft":
+		return NewLocalKraftKafka(c)
 	case "app-interface":
 		return NewAppInterface(c)
 	case "managed":
@@ -48,6 +53,13 @@ func getKafkaUsername(env *crd.ClowdEnvironment, app *crd.ClowdApp) string {
 	return fmt.Sprintf("%s-%s", env.Name, app.Name)
 }
 
+// getConsumerGroup returns the consumer group an app is permitted to use, the app may also use
+// any group prefixed by this name and a hyphen. The group is scoped by the app's namespace, so
+// that apps of the same name in different environments sharing a cluster do not share groups.
+func getConsumerGroup(app *crd.ClowdApp) string {
+	return fmt.Sprintf("%s-%s", app.Namespace, app.Name)
+}
+
 func getKafkaName(e *crd.ClowdEnvironment) string {
 	if e.Spec.Providers.Kafka.Cluster.Name == "" {
 		return e.Name
@@ -2,8 +2,10 @@ package kafka
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1/common"
@@ -20,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // KafkaTopic is the resource ident for a KafkaTopic object.
@@ -43,6 +46,10 @@ var KafkaMetricsConfigMap = providers.NewSingleResourceIdent(ProvName, "kafka_me
 // KafkaNetworkPolicy is the resource ident for the KafkaNetworkPolicy
 var KafkaNetworkPolicy = providers.NewSingleResourceIdent(ProvName, "kafka_network_policy", &networking.NetworkPolicy{})
 
+const externalListenerName = "external"
+
+const externalListenerPort = 9094
+
 var conversionMap = map[string]func([]string) (string, error){
 	"retention.ms":          utils.IntMax,
 	"retention.bytes":       utils.IntMax,
@@ -50,6 +57,32 @@ var conversionMap = map[string]func([]string) (string, error){
 	"cleanup.policy":        utils.ListMerge,
 }
 
+// conversionStrategies are the named strategies which additional topic config keys can be
+// registered against in the Clowder config, without adding them to the conversionMap.
+var conversionStrategies = map[string]func([]string) (string, error){
+	"max":   utils.IntMax,
+	"min":   utils.IntMin,
+	"merge": utils.ListMerge,
+}
+
+func getConversion(key string) (func([]string) (string, error), error) {
+	if f, ok := conversionMap[key]; ok {
+		return f, nil
+	}
+
+	strategy, ok := clowder_config.LoadedConfig.Settings.KafkaTopicConfigConversions[key]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("no conversion type for %s", key))
+	}
+
+	f, ok := conversionStrategies[strategy]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown conversion type %s for %s", strategy, key))
+	}
+
+	return f, nil
+}
+
 type strimziProvider struct {
 	providers.Provider
 	Config config.KafkaConfig
@@ -126,12 +159,19 @@ func (s *strimziProvider) configureKafkaCluster() error {
 
 	metrics.UnmarshalJSON(metricsData)
 
+	metricsCMName, metricsCMKey, err := s.getMetricsConfigMapKeyRef(
+		s.Env.Spec.Providers.Kafka.Cluster.MetricsConfigMapRef, clusterNN.Namespace, cmnn.Name,
+	)
+	if err != nil {
+		return err
+	}
+
 	metricsConfig := strimzi.KafkaSpecKafkaMetricsConfig{
 		Type: "jmxPrometheusExporter",
 		ValueFrom: strimzi.KafkaSpecKafkaMetricsConfigValueFrom{
 			ConfigMapKeyRef: &strimzi.KafkaSpecKafkaMetricsConfigValueFromConfigMapKeyRef{
-				Key:      common.StringPtr("metrics"),
-				Name:     common.StringPtr(cmnn.Name),
+				Key:      common.StringPtr(metricsCMKey),
+				Name:     common.StringPtr(metricsCMName),
 				Optional: common.FalsePtr(),
 			},
 		},
@@ -155,6 +195,9 @@ func (s *strimziProvider) configureKafkaCluster() error {
 		listener.Authentication = &strimzi.KafkaSpecKafkaListenersElemAuthentication{
 			Type: strimzi.KafkaSpecKafkaListenersElemAuthenticationTypeScramSha512,
 		}
+		if useMtls(s.Env) {
+			listener.Authentication.Type = strimzi.KafkaSpecKafkaListenersElemAuthenticationTypeTls
+		}
 		k.Spec.Kafka.Authorization = &strimzi.KafkaSpecKafkaAuthorization{
 			Type: strimzi.KafkaSpecKafkaAuthorizationTypeSimple,
 		}
@@ -162,6 +205,14 @@ func (s *strimziProvider) configureKafkaCluster() error {
 
 	k.Spec.Kafka.Listeners = []strimzi.KafkaSpecKafkaListenersElem{listener}
 
+	if external := s.Env.Spec.Providers.Kafka.Cluster.ExternalListener; external != nil {
+		externalListener, err := buildExternalListener(external, listener, getKafkaName(s.Env), replicas)
+		if err != nil {
+			return err
+		}
+		k.Spec.Kafka.Listeners = append(k.Spec.Kafka.Listeners, externalListener)
+	}
+
 	if s.Env.Spec.Providers.Kafka.PVC {
 		k.Spec.Kafka.Storage = strimzi.KafkaSpecKafkaStorage{
 			Type:        strimzi.KafkaSpecKafkaStorageTypePersistentClaim,
@@ -208,6 +259,59 @@ func (s *strimziProvider) configureKafkaCluster() error {
 	return nil
 }
 
+// buildExternalListener returns a listener exposing the cluster outside of the k8s cluster, using
+// the same TLS and authentication settings as the cluster's internal listener.
+func buildExternalListener(
+	cfg *crd.KafkaExternalListenerConfig,
+	internal strimzi.KafkaSpecKafkaListenersElem,
+	clusterName string,
+	replicas int32,
+) (strimzi.KafkaSpecKafkaListenersElem, error) {
+	listener := strimzi.KafkaSpecKafkaListenersElem{
+		Name:           externalListenerName,
+		Port:           externalListenerPort,
+		Tls:            internal.Tls,
+		Authentication: internal.Authentication,
+	}
+
+	switch cfg.Type {
+	case "nodeport":
+		listener.Type = strimzi.KafkaSpecKafkaListenersElemTypeNodeport
+	case "ingress":
+		if cfg.Domain == "" {
+			return listener, errors.New("kafka external ingress listener requires a domain")
+		}
+
+		class := cfg.Class
+		if class == "" {
+			class = "nginx"
+		}
+
+		bootstrapHost := fmt.Sprintf("%s-bootstrap.%s", clusterName, cfg.Domain)
+
+		// ingress listeners rely on TLS passthrough to route to the right broker
+		listener.Type = strimzi.KafkaSpecKafkaListenersElemTypeIngress
+		listener.Tls = true
+		listener.Configuration = &strimzi.KafkaSpecKafkaListenersElemConfiguration{
+			Class:     &class,
+			Bootstrap: &strimzi.KafkaSpecKafkaListenersElemConfigurationBootstrap{Host: &bootstrapHost},
+			Brokers:   []strimzi.KafkaSpecKafkaListenersElemConfigurationBrokersElem{},
+		}
+
+		for i := int32(0); i < replicas; i++ {
+			brokerHost := fmt.Sprintf("%s-broker-%d.%s", clusterName, i, cfg.Domain)
+			listener.Configuration.Brokers = append(
+				listener.Configuration.Brokers,
+				strimzi.KafkaSpecKafkaListenersElemConfigurationBrokersElem{Broker: i, Host: &brokerHost},
+			)
+		}
+	default:
+		return listener, errors.New(fmt.Sprintf("unknown kafka external listener type %s", cfg.Type))
+	}
+
+	return listener, nil
+}
+
 func (s *strimziProvider) createKafkaMetricsConfigMap() (types.NamespacedName, error) {
 	cm := &core.ConfigMap{}
 	nn := types.NamespacedName{
@@ -265,7 +369,7 @@ func (s *strimziProvider) createKafkaConnectUser() error {
 
 	ku.Spec = &strimzi.KafkaUserSpec{
 		Authentication: &strimzi.KafkaUserSpecAuthentication{
-			Type: strimzi.KafkaUserSpecAuthenticationTypeScramSha512,
+			Type: getKafkaUserAuthenticationType(s.Env),
 		},
 		Authorization: &strimzi.KafkaUserSpecAuthorization{
 			Acls: []strimzi.KafkaUserSpecAuthorizationAclsElem{},
@@ -305,7 +409,10 @@ func (s *strimziProvider) createKafkaConnectUser() error {
 	return nil
 }
 
-func (s *strimziProvider) configureKafkaConnectCluster() error {
+// configureKafkaConnectCluster creates the environment's KafkaConnect cluster. The secrets config
+// provider, used to pass database passwords to connectors, is only configured when an app declares
+// connectors, so that clusters without any are neither changed nor restarted for it.
+func (s *strimziProvider) configureKafkaConnectCluster(connectorsDeclared bool) error {
 	clusterNN := types.NamespacedName{
 		Namespace: getConnectNamespace(s.Env),
 		Name:      getConnectClusterName(s.Env),
@@ -320,6 +427,18 @@ func (s *strimziProvider) configureKafkaConnectCluster() error {
 		return err
 	}
 
+	cmnn, err := s.createKafkaConnectMetricsConfigMap()
+	if err != nil {
+		return err
+	}
+
+	metricsCMName, metricsCMKey, err := s.getMetricsConfigMapKeyRef(
+		s.Env.Spec.Providers.Kafka.Connect.MetricsConfigMapRef, clusterNN.Namespace, cmnn.Name,
+	)
+	if err != nil {
+		return err
+	}
+
 	// ensure that connect cluster of this same name but labelled for different env does not exist
 	if envLabel, ok := k.GetLabels()["env"]; ok {
 		if envLabel != s.Env.Name {
@@ -362,6 +481,20 @@ func (s *strimziProvider) configureKafkaConnectCluster() error {
 			"status.storage.replication.factor": "1",
 		},
 		Image: &image,
+		MetricsConfig: &strimzi.KafkaConnectSpecMetricsConfig{
+			Type: strimzi.KafkaConnectSpecMetricsConfigTypeJmxPrometheusExporter,
+			ValueFrom: strimzi.KafkaConnectSpecMetricsConfigValueFrom{
+				ConfigMapKeyRef: &strimzi.KafkaConnectSpecMetricsConfigValueFromConfigMapKeyRef{
+					Key:      common.StringPtr(metricsCMKey),
+					Name:     common.StringPtr(metricsCMName),
+					Optional: common.FalsePtr(),
+				},
+			},
+		},
+	}
+	if connectorsDeclared {
+		k.Spec.Config["config.providers"] = "secrets"
+		k.Spec.Config["config.providers.secrets.class"] = connectSecretConfigProvider
 	}
 	if !s.Env.Spec.Providers.Kafka.EnableLegacyStrimzi {
 		k.Spec.Tls = &strimzi.KafkaConnectSpecTls{
@@ -370,13 +503,24 @@ func (s *strimziProvider) configureKafkaConnectCluster() error {
 				SecretName:  fmt.Sprintf("%s-cluster-ca-cert", getKafkaName(s.Env)),
 			}},
 		}
-		k.Spec.Authentication = &strimzi.KafkaConnectSpecAuthentication{
-			PasswordSecret: &strimzi.KafkaConnectSpecAuthenticationPasswordSecret{
-				Password:   "password",
-				SecretName: username,
-			},
-			Type:     "scram-sha-512",
-			Username: &username,
+		if useMtls(s.Env) {
+			k.Spec.Authentication = &strimzi.KafkaConnectSpecAuthentication{
+				CertificateAndKey: &strimzi.KafkaConnectSpecAuthenticationCertificateAndKey{
+					Certificate: "user.crt",
+					Key:         "user.key",
+					SecretName:  username,
+				},
+				Type: strimzi.KafkaConnectSpecAuthenticationTypeTls,
+			}
+		} else {
+			k.Spec.Authentication = &strimzi.KafkaConnectSpecAuthentication{
+				PasswordSecret: &strimzi.KafkaConnectSpecAuthenticationPasswordSecret{
+					Password:   "password",
+					SecretName: username,
+				},
+				Type:     "scram-sha-512",
+				Username: &username,
+			}
 		}
 	}
 
@@ -429,9 +573,14 @@ func (s *strimziProvider) configureListeners() error {
 	kafkaCACert := string(kafkaCASecret.Data["ca.crt"])
 
 	s.Config.Brokers = []config.BrokerConfig{}
+	s.Env.Status.KafkaExternalBootstrapServers = ""
 	for _, listener := range kafkaResource.Status.Listeners {
-		if listener.Type != nil && *listener.Type == "tls" {
-			s.Config.Brokers = append(s.Config.Brokers, buildTlsBrokerConfig(listener, kafkaCACert))
+		if listener.Type != nil && *listener.Type == externalListenerName {
+			if listener.BootstrapServers != nil {
+				s.Env.Status.KafkaExternalBootstrapServers = *listener.BootstrapServers
+			}
+		} else if listener.Type != nil && *listener.Type == "tls" {
+			s.Config.Brokers = append(s.Config.Brokers, buildTlsBrokerConfig(listener, kafkaCACert, useMtls(s.Env)))
 		} else if listener.Type != nil && (*listener.Type == "plain" || *listener.Type == "tcp") {
 			s.Config.Brokers = append(s.Config.Brokers, buildTcpBrokerConfig(listener))
 		}
@@ -458,14 +607,18 @@ func buildTcpBrokerConfig(listener strimzi.KafkaStatusListenersElem) config.Brok
 	return bc
 }
 
-func buildTlsBrokerConfig(listener strimzi.KafkaStatusListenersElem, caCert string) config.BrokerConfig {
+func buildTlsBrokerConfig(listener strimzi.KafkaStatusListenersElem, caCert string, mtls bool) config.BrokerConfig {
 	authType := config.BrokerConfigAuthtypeSasl
 	bc := config.BrokerConfig{
-		Sasl:     &config.KafkaSASLConfig{},
 		Cacert:   &caCert,
 		Hostname: *listener.Addresses[0].Host,
 		Authtype: &authType,
 	}
+	if mtls {
+		authType = config.BrokerConfigAuthtypeMtls
+	} else {
+		bc.Sasl = &config.KafkaSASLConfig{}
+	}
 	port := listener.Addresses[0].Port
 	if port != nil {
 		p := int(*port)
@@ -474,7 +627,7 @@ func buildTlsBrokerConfig(listener strimzi.KafkaStatusListenersElem, caCert stri
 	return bc
 }
 
-func (s *strimziProvider) configureBrokers() error {
+func (s *strimziProvider) configureBrokers(connectorsDeclared bool) error {
 	if err := s.configureKafkaCluster(); err != nil {
 		return errors.Wrap("failed to provision kafka cluster", err)
 	}
@@ -487,10 +640,16 @@ func (s *strimziProvider) configureBrokers() error {
 		return clowdErr
 	}
 
-	if err := s.configureKafkaConnectCluster(); err != nil {
+	if err := s.configureKafkaConnectCluster(connectorsDeclared); err != nil {
 		return errors.Wrap("failed to provision kafka connect cluster", err)
 	}
 
+	if s.Env.Spec.Providers.Metrics.Mode == "operator" {
+		if err := s.createPodMonitors(); err != nil {
+			return errors.Wrap("failed to create kafka pod monitors", err)
+		}
+	}
+
 	return nil
 }
 
@@ -507,7 +666,38 @@ func NewStrimzi(p *providers.Provider) (providers.ClowderProvider, error) {
 		return nil, err
 	}
 
-	return kafkaProvider, kafkaProvider.configureBrokers()
+	appList, err := p.Env.GetAppsInEnv(p.Ctx, p.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	connectorsDeclared := len(getConnectorRequests(p.Env, appList)) > 0
+
+	return kafkaProvider, kafkaProvider.configureBrokers(connectorsDeclared)
+}
+
+// EnvProvide applies the environment's topic retention policy to topics no longer used by any app,
+// deletes the connectors no longer declared by any app, and records the topics with conflicting
+// settings in the environment's status. The status is only persisted by the environment reconcile,
+// so it is not computed when reconciling apps.
+func (s *strimziProvider) EnvProvide() (time.Duration, error) {
+	appList, err := s.Env.GetAppsInEnv(s.Ctx, s.Client)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := gcConnectors(&s.Provider, getConnectorRequests(s.Env, appList)); err != nil {
+		return 0, err
+	}
+
+	requests, err := getTopicRequests(s.Env, appList)
+	if err != nil {
+		return 0, err
+	}
+
+	setTopicConflictStatus(s.Env, requests)
+
+	return gcTopics(&s.Provider, requests)
 }
 
 func createNetworkPolicies(p *providers.Provider) error {
@@ -549,6 +739,14 @@ func createNetworkPolicies(p *providers.Provider) error {
 		From: npFrom,
 	}}
 
+	// the external listener must be reachable from outside of the app namespaces
+	if p.Env.Spec.Providers.Kafka.Cluster.ExternalListener != nil {
+		port := intstr.FromInt(externalListenerPort)
+		np.Spec.Ingress = append(np.Spec.Ingress, networking.NetworkPolicyIngressRule{
+			Ports: []networking.NetworkPolicyPort{{Port: &port}},
+		})
+	}
+
 	np.Spec.PolicyTypes = []networking.PolicyType{"Ingress"}
 
 	labeler := utils.GetCustomLabeler(nil, nn, p.Env)
@@ -569,9 +767,15 @@ func (s *strimziProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error
 		if err != nil {
 			return err
 		}
+	} else {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.CyndiPipelineValid)
 	}
 
-	if len(app.Spec.KafkaTopics) == 0 {
+	if err := s.processConnectors(app, c); err != nil {
+		return err
+	}
+
+	if len(getRequestedTopics(s.Env, app)) == 0 {
 		return nil
 	}
 
@@ -583,7 +787,11 @@ func (s *strimziProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error
 		return err
 	}
 
-	if err := s.setBrokerCredentials(app); err != nil {
+	if useMtls(s.Env) {
+		if err := s.setBrokerCertificates(app); err != nil {
+			return err
+		}
+	} else if err := s.setBrokerCredentials(app); err != nil {
 		return err
 	}
 
@@ -660,7 +868,7 @@ func (s *strimziProvider) createKafkaUser(app *crd.ClowdApp) error {
 
 	ku.Spec = &strimzi.KafkaUserSpec{
 		Authentication: &strimzi.KafkaUserSpecAuthentication{
-			Type: strimzi.KafkaUserSpecAuthenticationTypeScramSha512,
+			Type: getKafkaUserAuthenticationType(s.Env),
 		},
 		Authorization: &strimzi.KafkaUserSpecAuthorization{
 			Acls: []strimzi.KafkaUserSpecAuthorizationAclsElem{},
@@ -668,39 +876,115 @@ func (s *strimziProvider) createKafkaUser(app *crd.ClowdApp) error {
 		},
 	}
 
-	address := "*"
-	patternType := strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypeLiteral
+	acls, err := buildKafkaUserAcls(app, *s.Env)
+	if err != nil {
+		return err
+	}
+
+	ku.Spec.Authorization.Acls = acls
+	ku.Spec.Quotas = buildKafkaUserQuotas(app, *s.Env)
+
+	if err := s.Cache.Update(KafkaUser, ku); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// buildKafkaUserQuotas returns the quotas for an app's KafkaUser. Each quota set on the app overrides
+// the environment's default for that quota. Nil is returned if no quotas apply.
+func buildKafkaUserQuotas(app *crd.ClowdApp, env crd.ClowdEnvironment) *strimzi.KafkaUserSpecQuotas {
+	quotas := env.Spec.Providers.Kafka.Quotas
+
+	if appQuotas := app.Spec.KafkaQuotas; appQuotas != nil {
+		if appQuotas.ConsumerByteRate != nil {
+			quotas.ConsumerByteRate = appQuotas.ConsumerByteRate
+		}
+		if appQuotas.ProducerByteRate != nil {
+			quotas.ProducerByteRate = appQuotas.ProducerByteRate
+		}
+		if appQuotas.RequestPercentage != nil {
+			quotas.RequestPercentage = appQuotas.RequestPercentage
+		}
+	}
+
+	if quotas == (strimzi.KafkaUserSpecQuotas{}) {
+		return nil
+	}
+
+	return &quotas
+}
 
-	for _, topic := range app.Spec.KafkaTopics {
-		topicName := getTopicName(topic, *s.Env, app.Namespace)
+// buildKafkaUserAcls returns the ACLs for an app's KafkaUser, granting only the operations needed
+// for the access requested on each topic, and consumer group access scoped to the app's groups.
+func buildKafkaUserAcls(app *crd.ClowdApp, env crd.ClowdEnvironment) ([]strimzi.KafkaUserSpecAuthorizationAclsElem, error) {
+	acls := []strimzi.KafkaUserSpecAuthorizationAclsElem{}
 
-		ku.Spec.Authorization.Acls = append(ku.Spec.Authorization.Acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
+	address := "*"
+	literal := strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypeLiteral
+	prefix := strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypePrefix
+
+	makeAcl := func(
+		op strimzi.KafkaUserSpecAuthorizationAclsElemOperation,
+		resType strimzi.KafkaUserSpecAuthorizationAclsElemResourceType,
+		name string,
+		patternType *strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternType,
+	) strimzi.KafkaUserSpecAuthorizationAclsElem {
+		return strimzi.KafkaUserSpecAuthorizationAclsElem{
 			Host:      &address,
-			Operation: strimzi.KafkaUserSpecAuthorizationAclsElemOperationAll,
+			Operation: op,
 			Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
-				Name:        &topicName,
-				PatternType: &patternType,
-				Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeTopic,
+				Name:        &name,
+				PatternType: patternType,
+				Type:        resType,
 			},
-		})
+		}
 	}
 
-	group := "*"
-	ku.Spec.Authorization.Acls = append(ku.Spec.Authorization.Acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
-		Host:      &address,
-		Operation: strimzi.KafkaUserSpecAuthorizationAclsElemOperationAll,
-		Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
-			Name:        &group,
-			PatternType: &patternType,
-			Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup,
-		},
-	})
+	consumes := false
 
-	if err := s.Cache.Update(KafkaUser, ku); err != nil {
-		return err
+	for _, topic := range getAppTopics(&env, app) {
+		topicName, err := getTopicName(topic, env, app.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		if topic.Access.CanProduce() {
+			acls = append(acls, makeAcl(
+				strimzi.KafkaUserSpecAuthorizationAclsElemOperationWrite,
+				strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeTopic,
+				topicName,
+				&literal,
+			))
+		}
+
+		if topic.Access.CanConsume() {
+			consumes = true
+			acls = append(acls, makeAcl(
+				strimzi.KafkaUserSpecAuthorizationAclsElemOperationRead,
+				strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeTopic,
+				topicName,
+				&literal,
+			))
+		}
 	}
 
-	return nil
+	if consumes {
+		group := getConsumerGroup(app)
+		acls = append(acls, makeAcl(
+			strimzi.KafkaUserSpecAuthorizationAclsElemOperationRead,
+			strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup,
+			group,
+			&literal,
+		), makeAcl(
+			strimzi.KafkaUserSpecAuthorizationAclsElemOperationRead,
+			strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup,
+			group+"-",
+			&prefix,
+		))
+	}
+
+	return acls, nil
 }
 
 func (s *strimziProvider) processTopics(app *crd.ClowdApp) error {
@@ -711,10 +995,19 @@ func (s *strimziProvider) processTopics(app *crd.ClowdApp) error {
 		return errors.Wrap("Topic creation failed: Error listing apps", err)
 	}
 
-	for _, topic := range app.Spec.KafkaTopics {
+	requests, err := getTopicRequests(s.Env, &appList)
+	if err != nil {
+		return err
+	}
+	appConflicts := map[string][]crd.KafkaTopicSettingConflict{}
+
+	for _, topic := range getAppTopics(s.Env, app) {
 		k := &strimzi.KafkaTopic{}
 
-		topicName := getTopicName(topic, *s.Env, app.Namespace)
+		topicName, err := getTopicName(topic, *s.Env, app.Namespace)
+		if err != nil {
+			return err
+		}
 		knn := types.NamespacedName{
 			Namespace: getKafkaNamespace(s.Env),
 			Name:      topicName,
@@ -727,126 +1020,148 @@ func (s *strimziProvider) processTopics(app *crd.ClowdApp) error {
 		labels := providers.Labels{
 			"strimzi.io/cluster": getKafkaName(s.Env),
 			"env":                app.Spec.EnvName,
-			// If we label it with the app name, since app names should be
-			// unique? can we use for delete selector?
 		}
 
 		k.SetName(topicName)
 		k.SetNamespace(getKafkaNamespace(s.Env))
-		// the ClowdEnvironment is the owner of this topic
+		// the ClowdEnvironment is the owner of this topic, the apps referencing it are labeled so
+		// that it can be cleaned up once the last of them goes away
 		k.SetOwnerReferences([]metav1.OwnerReference{s.Env.MakeOwnerReference()})
 		k.SetLabels(labels)
+		topicRequests, ok := requests[topicName]
+		if !ok {
+			topicRequests = []topicRequest{{App: app.Name, Spec: topic}}
+		}
+
+		setTopicAppLabels(k, getRequestingApps(topicRequests))
+		delete(k.GetAnnotations(), topicOrphanedAnnotation)
 
 		k.Spec = &strimzi.KafkaTopicSpec{
 			Config: make(map[string]string),
 		}
 
-		// This can be improved from an efficiency PoV
-		// Loop through all key/value pairs in the config
-		replicaValList := []string{}
-		partitionValList := []string{}
-
-		err := processTopicValues(k, s.Env, app, appList, topic, replicaValList, partitionValList)
+		conflicts, err := processTopicValues(k, s.Env, topicRequests)
 
 		if err != nil {
 			return err
 		}
 
+		if len(conflicts) > 0 {
+			appConflicts[topicName] = conflicts
+		}
+
 		if err := s.Cache.Update(KafkaTopic, k); err != nil {
 			return err
 		}
 
-		topicConfig = append(
-			topicConfig,
-			config.TopicConfig{Name: topicName, RequestedName: topic.TopicName},
-		)
+		tc := config.TopicConfig{Name: topicName, RequestedName: topic.TopicName}
+		if topic.Access.CanConsume() {
+			group := getConsumerGroup(app)
+			tc.ConsumerGroup = &group
+		}
+
+		topicConfig = append(topicConfig, tc)
 	}
 
 	s.Config.Topics = topicConfig
 
+	setTopicConflictCondition(app, appConflicts)
+
 	return nil
 }
 
-func getTopicName(topic crd.KafkaTopicSpec, env crd.ClowdEnvironment, namespace string) string {
+func getTopicName(topic crd.KafkaTopicSpec, env crd.ClowdEnvironment, namespace string) (string, error) {
+	if name, ok, err := getTemplatedTopicName(topic.TopicName, &env, namespace); ok || err != nil {
+		return name, err
+	}
 	if clowder_config.LoadedConfig.Features.UseComplexStrimziTopicNames {
-		return fmt.Sprintf("%s-%s-%s", topic.TopicName, env.Name, namespace)
+		return fmt.Sprintf("%s-%s-%s", topic.TopicName, env.Name, namespace), nil
 	} else {
-		return topic.TopicName
+		return topic.TopicName, nil
 	}
 }
 
+// processTopicValues resolves the settings requested for a topic by every app in the environment
+// onto the KafkaTopic, and returns any settings that were requested with differing values.
 func processTopicValues(
 	k *strimzi.KafkaTopic,
 	env *crd.ClowdEnvironment,
-	app *crd.ClowdApp,
-	appList crd.ClowdAppList,
-	topic crd.KafkaTopicSpec,
-	replicaValList []string,
-	partitionValList []string,
-) error {
+	requests []topicRequest,
+) ([]crd.KafkaTopicSettingConflict, error) {
 
-	for _, iapp := range appList.Items {
+	conflicts := []crd.KafkaTopicSettingConflict{}
 
-		if app.Spec.Pods != nil {
-			app.ConvertToNewShim()
+	replicaValList := []string{}
+	partitionValList := []string{}
+	replicaVals := map[string]string{}
+	partitionVals := map[string]string{}
+	keys := []string{}
+
+	for _, request := range requests {
+		replicaValList = append(replicaValList, strconv.Itoa(int(request.Spec.Replicas)))
+		partitionValList = append(partitionValList, strconv.Itoa(int(request.Spec.Partitions)))
+
+		if request.Spec.Replicas != 0 {
+			replicaVals[request.App] = strconv.Itoa(int(request.Spec.Replicas))
+		}
+		if request.Spec.Partitions != 0 {
+			partitionVals[request.App] = strconv.Itoa(int(request.Spec.Partitions))
 		}
 
-		if iapp.Spec.EnvName != app.Spec.EnvName {
-			// Only consider apps within this ClowdEnvironment
-			continue
+		for key := range request.Spec.Config {
+			keys = append(keys, key)
 		}
-		if iapp.Spec.KafkaTopics != nil {
-			for _, itopic := range iapp.Spec.KafkaTopics {
-				if itopic.TopicName != topic.TopicName {
-					// Only consider a topic that matches the name
-					continue
-				}
-				replicaValList = append(replicaValList, strconv.Itoa(int(itopic.Replicas)))
-				partitionValList = append(partitionValList, strconv.Itoa(int(itopic.Partitions)))
-			}
+	}
+
+	for _, conflict := range []*crd.KafkaTopicSettingConflict{
+		findTopicConflict("partitions", partitionVals),
+		findTopicConflict("replicas", replicaVals),
+	} {
+		if conflict != nil {
+			conflicts = append(conflicts, *conflict)
 		}
 	}
 
-	for key := range topic.Config {
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		if i > 0 && keys[i-1] == key {
+			continue
+		}
+
 		valList := []string{}
-		for _, iapp := range appList.Items {
-			if iapp.Spec.EnvName != app.Spec.EnvName {
-				// Only consider apps within this ClowdEnvironment
-				continue
-			}
-			if iapp.Spec.KafkaTopics != nil {
-				for _, itopic := range app.Spec.KafkaTopics {
-					if itopic.TopicName != topic.TopicName {
-						// Only consider a topic that matches the name
-						continue
-					}
-					replicaValList = append(replicaValList, strconv.Itoa(int(itopic.Replicas)))
-					partitionValList = append(partitionValList, strconv.Itoa(int(itopic.Partitions)))
-					if itopic.Config != nil {
-						if val, ok := itopic.Config[key]; ok {
-							valList = append(valList, val)
-						}
-					}
-				}
+		vals := map[string]string{}
+		for _, request := range requests {
+			if val, ok := request.Spec.Config[key]; ok {
+				valList = append(valList, val)
+				vals[request.App] = val
 			}
 		}
-		f, ok := conversionMap[key]
-		if ok {
-			out, _ := f(valList)
-			k.Spec.Config[key] = out
-		} else {
-			return errors.New(fmt.Sprintf("no conversion type for %s", key))
+
+		f, err := getConversion(key)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := f(valList)
+		if err != nil {
+			return nil, errors.Wrap(fmt.Sprintf("could not convert values for %s", key), err)
+		}
+		k.Spec.Config[key] = out
+
+		if conflict := findTopicConflict(key, vals); conflict != nil {
+			conflicts = append(conflicts, *conflict)
 		}
 	}
 
 	if len(replicaValList) > 0 {
 		maxReplicas, err := utils.IntMax(replicaValList)
 		if err != nil {
-			return errors.New(fmt.Sprintf("could not compute max for %v", replicaValList))
+			return nil, errors.New(fmt.Sprintf("could not compute max for %v", replicaValList))
 		}
 		maxReplicasInt, err := common.Atoi32(maxReplicas)
 		if err != nil {
-			return errors.New(fmt.Sprintf("could not convert string to int32 for %v", maxReplicas))
+			return nil, errors.New(fmt.Sprintf("could not convert string to int32 for %v", maxReplicas))
 		}
 		k.Spec.Replicas = maxReplicasInt
 		if k.Spec.Replicas < int32(1) {
@@ -858,11 +1173,11 @@ func processTopicValues(
 	if len(partitionValList) > 0 {
 		maxPartitions, err := utils.IntMax(partitionValList)
 		if err != nil {
-			return errors.New(fmt.Sprintf("could not compute max for %v", partitionValList))
+			return nil, errors.New(fmt.Sprintf("could not compute max for %v", partitionValList))
 		}
 		maxPartitionsInt, err := common.Atoi32(maxPartitions)
 		if err != nil {
-			return errors.New(fmt.Sprintf("could not convert to string to int32 for %v", maxPartitions))
+			return nil, errors.New(fmt.Sprintf("could not convert to string to int32 for %v", maxPartitions))
 		}
 		k.Spec.Partitions = maxPartitionsInt
 		if k.Spec.Partitions < int32(1) {
@@ -877,5 +1192,5 @@ func processTopicValues(
 		k.Spec.Replicas = env.Spec.Providers.Kafka.Cluster.Replicas
 	}
 
-	return nil
+	return conflicts, nil
 }
@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1/common"
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKafkaUserAcls(t *testing.T) {
+	env := getKafkaTestEnv()
+	app := crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "app-ns",
+		},
+		Spec: crd.ClowdAppSpec{
+			KafkaTopics: []crd.KafkaTopicSpec{
+				{TopicName: "produced", Access: crd.KafkaTopicAccessProduce},
+				{TopicName: "consumed", Access: crd.KafkaTopicAccessConsume},
+				{TopicName: "both"},
+			},
+		},
+	}
+
+	acls, err := buildKafkaUserAcls(&app, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type acl struct {
+		op   strimzi.KafkaUserSpecAuthorizationAclsElemOperation
+		kind strimzi.KafkaUserSpecAuthorizationAclsElemResourceType
+		name string
+	}
+
+	expected := []acl{
+		{"Write", "topic", "produced"},
+		{"Read", "topic", "consumed"},
+		{"Write", "topic", "both"},
+		{"Read", "topic", "both"},
+		{"Read", "group", "app-ns-app"},
+		{"Read", "group", "app-ns-app-"},
+	}
+
+	if len(acls) != len(expected) {
+		t.Fatalf("Wrong number of acls %d; expected %d", len(acls), len(expected))
+	}
+
+	for i, e := range expected {
+		got := acl{acls[i].Operation, acls[i].Resource.Type, *acls[i].Resource.Name}
+		if got != e {
+			t.Errorf("Wrong acl %v; expected %v", got, e)
+		}
+		if acls[i].Operation == strimzi.KafkaUserSpecAuthorizationAclsElemOperationAll {
+			t.Errorf("Acl %v should not grant All", got)
+		}
+	}
+
+	if *acls[5].Resource.PatternType != strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypePrefix {
+		t.Errorf("Consumer group prefix acl was not a prefix pattern")
+	}
+}
+
+func TestKafkaUserQuotas(t *testing.T) {
+	env := getKafkaTestEnv()
+	app := crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "app-ns",
+		},
+	}
+
+	if quotas := buildKafkaUserQuotas(&app, env); quotas != nil {
+		t.Errorf("Expected no quotas, got %+v", quotas)
+	}
+
+	env.Spec.Providers.Kafka.Quotas = strimzi.KafkaUserSpecQuotas{
+		ProducerByteRate:  common.Int32Ptr(1048576),
+		RequestPercentage: common.Int32Ptr(50),
+	}
+	app.Spec.KafkaQuotas = &strimzi.KafkaUserSpecQuotas{
+		ProducerByteRate: common.Int32Ptr(2097152),
+		ConsumerByteRate: common.Int32Ptr(4194304),
+	}
+
+	quotas := buildKafkaUserQuotas(&app, env)
+
+	if quotas == nil {
+		t.Fatal("Expected quotas")
+	}
+	if *quotas.ProducerByteRate != 2097152 {
+		t.Errorf("Wrong producer byte rate %d; expected %d", *quotas.ProducerByteRate, 2097152)
+	}
+	if *quotas.ConsumerByteRate != 4194304 {
+		t.Errorf("Wrong consumer byte rate %d; expected %d", *quotas.ConsumerByteRate, 4194304)
+	}
+	if *quotas.RequestPercentage != 50 {
+		t.Errorf("Wrong request percentage %d; expected %d", *quotas.RequestPercentage, 50)
+	}
+	if *env.Spec.Providers.Kafka.Quotas.ProducerByteRate != 1048576 {
+		t.Error("Environment default quotas were modified")
+	}
+}
+
+func TestExternalListener(t *testing.T) {
+	internal := strimzi.KafkaSpecKafkaListenersElem{
+		Name: "tls",
+		Port: 9093,
+		Tls:  true,
+		Authentication: &strimzi.KafkaSpecKafkaListenersElemAuthentication{
+			Type: strimzi.KafkaSpecKafkaListenersElemAuthenticationTypeScramSha512,
+		},
+	}
+
+	listener, err := buildExternalListener(&crd.KafkaExternalListenerConfig{Type: "nodeport"}, internal, "kafka", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listener.Type != strimzi.KafkaSpecKafkaListenersElemTypeNodeport || listener.Name != "external" {
+		t.Errorf("Wrong nodeport listener %+v", listener)
+	}
+	if listener.Authentication != internal.Authentication || !listener.Tls {
+		t.Error("External listener should use the internal listener's authentication")
+	}
+
+	listener, err = buildExternalListener(
+		&crd.KafkaExternalListenerConfig{Type: "ingress", Domain: "apps.example.com"}, internal, "kafka", 3,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *listener.Configuration.Bootstrap.Host != "kafka-bootstrap.apps.example.com" {
+		t.Errorf("Wrong bootstrap host %s", *listener.Configuration.Bootstrap.Host)
+	}
+	if len(listener.Configuration.Brokers) != 3 || *listener.Configuration.Brokers[2].Host != "kafka-broker-2.apps.example.com" {
+		t.Errorf("Wrong broker hosts %+v", listener.Configuration.Brokers)
+	}
+	if *listener.Configuration.Class != "nginx" {
+		t.Errorf("Wrong ingress class %s", *listener.Configuration.Class)
+	}
+
+	if _, err := buildExternalListener(&crd.KafkaExternalListenerConfig{Type: "ingress"}, internal, "kafka", 1); err == nil {
+		t.Error("Expected an error for an ingress listener without a domain")
+	}
+}
+
+func TestMetricsConfigMapKeyRefDefault(t *testing.T) {
+	s := &strimziProvider{}
+
+	name, key, err := s.getMetricsConfigMapKeyRef(nil, "kafka-ns", "env-metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "env-metrics" || key != "metrics" {
+		t.Errorf("Wrong metrics configmap ref %s/%s", name, key)
+	}
+}
+
+func TestPodMonitorSpec(t *testing.T) {
+	spec := makePodMonitorSpec("env-connect", "KafkaConnect", "connect-ns")
+
+	if len(spec.PodMetricsEndpoints) != 1 || spec.PodMetricsEndpoints[0].Port != "tcp-prometheus" {
+		t.Errorf("Wrong pod metrics endpoints %+v", spec.PodMetricsEndpoints)
+	}
+	if spec.Selector.MatchLabels["strimzi.io/cluster"] != "env-connect" || spec.Selector.MatchLabels["strimzi.io/kind"] != "KafkaConnect" {
+		t.Errorf("Wrong selector %+v", spec.Selector.MatchLabels)
+	}
+	if len(spec.NamespaceSelector.MatchNames) != 1 || spec.NamespaceSelector.MatchNames[0] != "connect-ns" {
+		t.Errorf("Wrong namespace selector %+v", spec.NamespaceSelector)
+	}
+}
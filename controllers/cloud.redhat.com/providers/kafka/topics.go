@@ -0,0 +1,498 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// topicAppLabelPrefix prefixes the label added to a KafkaTopic for each app which references it.
+const topicAppLabelPrefix = "app.kafka.cloud.redhat.com/"
+
+// getTopicAppLabel returns the label added to a KafkaTopic for an app which references it. The
+// name of a label is limited to 63 characters, so longer app names are truncated and suffixed with
+// a hash of the full name to keep them distinct.
+func getTopicAppLabel(app string) string {
+	if len(app) <= validation.DNS1123LabelMaxLength {
+		return topicAppLabelPrefix + app
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(app)))[:8]
+	return fmt.Sprintf("%s%s-%s", topicAppLabelPrefix, app[:validation.DNS1123LabelMaxLength-len(hash)-1], hash)
+}
+
+// topicOrphanedAnnotation records when a KafkaTopic stopped being referenced by any app.
+const topicOrphanedAnnotation = "clowder/topic-orphaned-at"
+
+// topicNameData is passed to the environment's topic naming template.
+type topicNameData struct {
+	Topic     string
+	Env       string
+	Namespace string
+}
+
+// maxTopicNameLength is the longest topic name accepted by Kafka.
+const maxTopicNameLength = 249
+
+var topicNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// topicNameTemplates caches the parsed topic naming templates, keyed by their text, so that each
+// is only parsed once rather than for every topic.
+var topicNameTemplates = map[string]*template.Template{}
+var topicNameTemplatesMutex sync.Mutex
+
+func getTopicNameTemplate(text string) (*template.Template, error) {
+	topicNameTemplatesMutex.Lock()
+	defer topicNameTemplatesMutex.Unlock()
+
+	if tmpl, ok := topicNameTemplates[text]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := template.New("topicName").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	topicNameTemplates[text] = tmpl
+	return tmpl, nil
+}
+
+// renderTopicName renders the environment's topic naming template for a topic and checks that the
+// result is a valid topic name. In operator mode the name is also used as the name of the
+// KafkaTopic CR, so must be a valid k8s object name too.
+func renderTopicName(env *crd.ClowdEnvironment, topicName string, namespace string) (string, error) {
+	tmpl, err := getTopicNameTemplate(env.Spec.Providers.Kafka.TopicNamingTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var name strings.Builder
+	if err := tmpl.Execute(&name, topicNameData{Topic: topicName, Env: env.Name, Namespace: namespace}); err != nil {
+		return "", err
+	}
+
+	if name.Len() == 0 {
+		return "", errors.New("template rendered an empty topic name")
+	}
+
+	if !topicNameRegexp.MatchString(name.String()) || name.Len() > maxTopicNameLength {
+		return "", errors.New(fmt.Sprintf(
+			"template rendered an invalid topic name %q, topic names must be at most %d characters of [a-zA-Z0-9._-]",
+			name.String(), maxTopicNameLength,
+		))
+	}
+
+	if env.Spec.Providers.Kafka.Mode == "operator" {
+		if errs := validation.IsDNS1123Subdomain(name.String()); len(errs) > 0 {
+			return "", errors.New(fmt.Sprintf(
+				"template rendered an invalid KafkaTopic name %q: %s", name.String(), strings.Join(errs, ", "),
+			))
+		}
+	}
+
+	return name.String(), nil
+}
+
+// validateTopicNamingTemplate returns an error if the environment's topic naming template cannot
+// be rendered.
+func validateTopicNamingTemplate(env *crd.ClowdEnvironment) error {
+	if env.Spec.Providers.Kafka.TopicNamingTemplate == "" {
+		return nil
+	}
+
+	if _, err := renderTopicName(env, "topic", "namespace"); err != nil {
+		return errors.Wrap("invalid kafka topicNamingTemplate", err)
+	}
+
+	return nil
+}
+
+// getTemplatedTopicName returns the name given to a topic by the environment's topic naming
+// template. False is returned if the environment has no template, in which case the mode's default
+// naming should be used.
+func getTemplatedTopicName(topicName string, env *crd.ClowdEnvironment, namespace string) (string, bool, error) {
+	if env.Spec.Providers.Kafka.TopicNamingTemplate == "" {
+		return "", false, nil
+	}
+
+	name, err := renderTopicName(env, topicName, namespace)
+	if err != nil {
+		return "", false, errors.Wrap(fmt.Sprintf("could not name topic %q", topicName), err)
+	}
+
+	return name, true, nil
+}
+
+// deadLetterRetention is the retention applied to dead-letter topics.
+const deadLetterRetention = 7 * 24 * time.Hour
+
+// retryTopicRetention is added to a retry topic's delay to give its retention.
+const retryTopicRetention = 24 * time.Hour
+
+// getRequestedTopics returns the topics requested by an app, followed by the topic it produces its
+// logs to when it uses kafka logging.
+func getRequestedTopics(env *crd.ClowdEnvironment, app *crd.ClowdApp) []crd.KafkaTopicSpec {
+	topics := append([]crd.KafkaTopicSpec{}, app.Spec.KafkaTopics...)
+
+	if env.UsesKafkaLogging(app) {
+		topics = append(topics, crd.KafkaTopicSpec{
+			TopicName:  env.GetLoggingTopicName(),
+			Access:     crd.KafkaTopicAccessProduce,
+			Partitions: env.Spec.Providers.Logging.Kafka.Partitions,
+			Replicas:   env.Spec.Providers.Logging.Kafka.Replicas,
+		})
+	}
+
+	return topics
+}
+
+// getAppTopics returns the topics requested by an app, followed by the dead-letter and retry
+// topics derived from them. Derived topics take the partitions and replicas of their parent, and
+// the app is given both produce and consume access to them.
+func getAppTopics(env *crd.ClowdEnvironment, app *crd.ClowdApp) []crd.KafkaTopicSpec {
+	topics := []crd.KafkaTopicSpec{}
+	derived := []crd.KafkaTopicSpec{}
+
+	for _, topic := range getRequestedTopics(env, app) {
+		topics = append(topics, topic)
+
+		makeDerived := func(name string, retention time.Duration) crd.KafkaTopicSpec {
+			return crd.KafkaTopicSpec{
+				TopicName:  name,
+				Access:     crd.KafkaTopicAccessBoth,
+				Partitions: topic.Partitions,
+				Replicas:   topic.Replicas,
+				Config: strimzi.KafkaTopicSpecConfig{
+					"retention.ms": strconv.FormatInt(retention.Milliseconds(), 10),
+				},
+			}
+		}
+
+		if topic.DeadLetter {
+			derived = append(derived, makeDerived(topic.TopicName+".dlq", deadLetterRetention))
+		}
+
+		for i, delay := range topic.RetryTopics {
+			name := fmt.Sprintf("%s.retry.%d", topic.TopicName, i+1)
+			derived = append(derived, makeDerived(name, delay.Duration+retryTopicRetention))
+		}
+	}
+
+	return append(topics, derived...)
+}
+
+// topicRequest is a single app's request for a topic.
+type topicRequest struct {
+	App  string
+	Spec crd.KafkaTopicSpec
+}
+
+// getTopicRequests returns a map of topic names to the requests made for them by the apps in the
+// environment, sorted by app name. Apps which are being deleted are not counted. An error is
+// returned if any topic cannot be named, rather than leaving its topics out and treating them as
+// unreferenced.
+func getTopicRequests(env *crd.ClowdEnvironment, appList *crd.ClowdAppList) (map[string][]topicRequest, error) {
+	requests := map[string][]topicRequest{}
+
+	for _, app := range appList.Items {
+		if app.Spec.EnvName != env.Name || app.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		for _, topic := range getAppTopics(env, &app) {
+			topicName, err := getTopicName(topic, *env, app.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			requests[topicName] = append(requests[topicName], topicRequest{App: app.Name, Spec: topic})
+		}
+	}
+
+	for _, topicRequests := range requests {
+		sort.SliceStable(topicRequests, func(i, j int) bool {
+			return topicRequests[i].App < topicRequests[j].App
+		})
+	}
+
+	return requests, nil
+}
+
+func getRequestingApps(requests []topicRequest) []string {
+	apps := []string{}
+	for _, request := range requests {
+		apps = append(apps, request.App)
+	}
+	return apps
+}
+
+// setTopicAppLabels replaces the app reference labels on the topic with one for each of the given
+// apps and returns true if the labels changed.
+func setTopicAppLabels(k *strimzi.KafkaTopic, apps []string) bool {
+	labels := k.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	wanted := map[string]bool{}
+	for _, app := range apps {
+		wanted[getTopicAppLabel(app)] = true
+	}
+
+	changed := false
+	for key := range labels {
+		if strings.HasPrefix(key, topicAppLabelPrefix) && !wanted[key] {
+			delete(labels, key)
+			changed = true
+		}
+	}
+	for key := range wanted {
+		if _, ok := labels[key]; !ok {
+			labels[key] = "true"
+			changed = true
+		}
+	}
+
+	k.SetLabels(labels)
+	return changed
+}
+
+// gcTopics applies the environment's topic retention policy to KafkaTopics which are no longer
+// referenced by any app, and refreshes the app reference labels on those that still are. The time
+// until the next orphaned topic is due to be deleted is returned, or zero if none are.
+func gcTopics(p *providers.Provider, requests map[string][]topicRequest) (time.Duration, error) {
+	retention := p.Env.Spec.Providers.Kafka.TopicRetention
+	var nextExpiry time.Duration
+
+	topicList := strimzi.KafkaTopicList{}
+	err := p.Client.List(
+		p.Ctx,
+		&topicList,
+		client.InNamespace(getKafkaNamespace(p.Env)),
+		client.MatchingLabels{"env": p.Env.Name, "strimzi.io/cluster": getKafkaName(p.Env)},
+	)
+	if err != nil {
+		return 0, errors.Wrap("Topic cleanup failed: Error listing topics", err)
+	}
+
+	for i := range topicList.Items {
+		topic := &topicList.Items[i]
+
+		if !isOwnedBy(topic, p.Env) {
+			continue
+		}
+
+		now := time.Now()
+
+		deleteTopic, update := applyTopicRetention(
+			topic, getRequestingApps(requests[topic.Name]), retention, now,
+		)
+
+		if deleteTopic {
+			if err := p.Client.Delete(p.Ctx, topic); err != nil {
+				return 0, errors.Wrap("Topic cleanup failed: Error deleting topic", err)
+			}
+			continue
+		}
+
+		if update {
+			if err := p.Client.Update(p.Ctx, topic); err != nil {
+				return 0, errors.Wrap("Topic cleanup failed: Error updating topic", err)
+			}
+		}
+
+		if expiry, ok := getTopicExpiry(topic, retention, now); ok && (nextExpiry == 0 || expiry < nextExpiry) {
+			nextExpiry = expiry
+		}
+	}
+
+	return nextExpiry, nil
+}
+
+// applyTopicRetention updates the topic's labels and annotations for the apps referencing it and
+// returns whether the topic should be deleted under the retention policy, and whether the topic
+// was modified.
+func applyTopicRetention(topic *strimzi.KafkaTopic, apps []string, retention crd.KafkaTopicRetentionConfig, now time.Time) (bool, bool) {
+	update := setTopicAppLabels(topic, apps)
+	annotations := topic.GetAnnotations()
+
+	if len(apps) > 0 {
+		if _, ok := annotations[topicOrphanedAnnotation]; ok {
+			delete(annotations, topicOrphanedAnnotation)
+			topic.SetAnnotations(annotations)
+			update = true
+		}
+		return false, update
+	}
+
+	switch retention.Policy {
+	case "delete":
+		return true, false
+	case "delete-after-duration":
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		orphanedAt, err := time.Parse(time.RFC3339, annotations[topicOrphanedAnnotation])
+		if err != nil {
+			annotations[topicOrphanedAnnotation] = now.UTC().Format(time.RFC3339)
+			topic.SetAnnotations(annotations)
+			return false, true
+		}
+
+		var duration time.Duration
+		if retention.Duration != nil {
+			duration = retention.Duration.Duration
+		}
+
+		if now.Sub(orphanedAt) >= duration {
+			return true, false
+		}
+	}
+
+	return false, update
+}
+
+// getTopicExpiry returns how long remains until an orphaned topic is deleted under the
+// delete-after-duration retention policy. False is returned if the topic is not awaiting deletion.
+func getTopicExpiry(topic *strimzi.KafkaTopic, retention crd.KafkaTopicRetentionConfig, now time.Time) (time.Duration, bool) {
+	if retention.Policy != "delete-after-duration" {
+		return 0, false
+	}
+
+	orphanedAt, err := time.Parse(time.RFC3339, topic.GetAnnotations()[topicOrphanedAnnotation])
+	if err != nil {
+		return 0, false
+	}
+
+	var duration time.Duration
+	if retention.Duration != nil {
+		duration = retention.Duration.Duration
+	}
+
+	expiry := orphanedAt.Add(duration).Sub(now)
+	if expiry <= 0 {
+		// the annotation is only at second precision, so an expiry may be a moment away
+		expiry = time.Second
+	}
+
+	return expiry, true
+}
+
+func isOwnedBy(obj metav1.Object, env *crd.ClowdEnvironment) bool {
+	for _, ownerRef := range obj.GetOwnerReferences() {
+		if ownerRef.UID == env.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// findTopicConflict returns a conflict if the apps requested differing values for the setting.
+func findTopicConflict(key string, values map[string]string) *crd.KafkaTopicSettingConflict {
+	distinct := map[string]bool{}
+	for _, val := range values {
+		distinct[val] = true
+	}
+
+	if len(distinct) < 2 {
+		return nil
+	}
+
+	return &crd.KafkaTopicSettingConflict{Key: key, Values: values}
+}
+
+// setTopicConflictCondition reports any conflicts on the app's topics as a condition on the app.
+func setTopicConflictCondition(app *crd.ClowdApp, conflicts map[string][]crd.KafkaTopicSettingConflict) {
+	condition := crd.ClowdCondition{
+		Type:    crd.KafkaTopicConfigConflicts,
+		Status:  core.ConditionFalse,
+		Reason:  "NoConflicts",
+		Message: "No other apps requested conflicting topic settings",
+	}
+
+	if len(conflicts) > 0 {
+		topicNames := []string{}
+		for topicName := range conflicts {
+			topicNames = append(topicNames, topicName)
+		}
+		sort.Strings(topicNames)
+
+		msgs := []string{}
+		for _, topicName := range topicNames {
+			for _, conflict := range conflicts[topicName] {
+				apps := []string{}
+				for app := range conflict.Values {
+					apps = append(apps, app)
+				}
+				sort.Strings(apps)
+
+				vals := []string{}
+				for _, app := range apps {
+					vals = append(vals, fmt.Sprintf("%s=%s", app, conflict.Values[app]))
+				}
+
+				msgs = append(msgs, fmt.Sprintf("%s %s requested as [%s]", topicName, conflict.Key, strings.Join(vals, ", ")))
+			}
+		}
+
+		condition.Status = core.ConditionTrue
+		condition.Reason = "ConflictingTopicSettings"
+		condition.Message = fmt.Sprintf(
+			"Topic settings were merged from conflicting requests, see the ClowdEnvironment status for the applied values: %s",
+			strings.Join(msgs, "; "),
+		)
+	}
+
+	crd.UpdateClowdAppCondition(&app.Status, &condition)
+}
+
+// setTopicConflictStatus records every topic in the environment with conflicting settings, along
+// with the resolved settings applied to it, in the environment's status.
+func setTopicConflictStatus(env *crd.ClowdEnvironment, requests map[string][]topicRequest) {
+	statuses := []crd.KafkaTopicConflictStatus{}
+
+	for topicName, topicRequests := range requests {
+		if len(topicRequests) < 2 {
+			continue
+		}
+
+		k := &strimzi.KafkaTopic{Spec: &strimzi.KafkaTopicSpec{Config: map[string]string{}}}
+
+		// Errors resolving a topic fail the reconciliation of the apps requesting it, so are
+		// reported there rather than here.
+		conflicts, err := processTopicValues(k, env, topicRequests)
+		if err != nil || len(conflicts) == 0 {
+			continue
+		}
+
+		statuses = append(statuses, crd.KafkaTopicConflictStatus{
+			TopicName:  topicName,
+			Partitions: k.Spec.Partitions,
+			Replicas:   k.Spec.Replicas,
+			Config:     k.Spec.Config,
+			Conflicts:  conflicts,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].TopicName < statuses[j].TopicName
+	})
+
+	env.Status.KafkaTopicConflicts = statuses
+}
@@ -0,0 +1,394 @@
+package kafka
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/clowder_config"
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestTopicRequests(t *testing.T) {
+	env := getKafkaTestEnv()
+	now := metav1.Now()
+
+	makeApp := func(name string, topics ...string) crd.ClowdApp {
+		app := crd.ClowdApp{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec:       crd.ClowdAppSpec{EnvName: "env"},
+		}
+		for _, topic := range topics {
+			app.Spec.KafkaTopics = append(app.Spec.KafkaTopics, crd.KafkaTopicSpec{TopicName: topic})
+		}
+		return app
+	}
+
+	deleted := makeApp("deleted", "shared", "gone")
+	deleted.SetDeletionTimestamp(&now)
+	other := makeApp("other", "shared")
+	other.Spec.EnvName = "other-env"
+
+	appList := crd.ClowdAppList{Items: []crd.ClowdApp{
+		makeApp("b", "shared"),
+		makeApp("a", "shared", "single"),
+		deleted,
+		other,
+	}}
+
+	requests, err := getTopicRequests(&env, &appList)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Wrong number of referenced topics %d; expected 2", len(requests))
+	}
+	apps := getRequestingApps(requests["shared"])
+	if len(apps) != 2 || apps[0] != "a" || apps[1] != "b" {
+		t.Errorf("Wrong references for shared topic %v", apps)
+	}
+	if _, ok := requests["gone"]; ok {
+		t.Errorf("Topic referenced only by a deleted app was counted")
+	}
+}
+
+func TestTopicRetention(t *testing.T) {
+	now := time.Now()
+
+	makeTopic := func() *strimzi.KafkaTopic {
+		k := &strimzi.KafkaTopic{}
+		k.SetLabels(map[string]string{
+			"env":                 "env",
+			getTopicAppLabel("a"): "true",
+		})
+		return k
+	}
+
+	t.Run("referenced", func(t *testing.T) {
+		k := makeTopic()
+		k.SetAnnotations(map[string]string{topicOrphanedAnnotation: now.Format(time.RFC3339)})
+
+		del, update := applyTopicRetention(k, []string{"b"}, crd.KafkaTopicRetentionConfig{Policy: "delete"}, now)
+
+		if del || !update {
+			t.Fatalf("Referenced topic should be updated, not deleted")
+		}
+		if _, ok := k.GetLabels()[getTopicAppLabel("a")]; ok {
+			t.Errorf("Stale app label was not removed")
+		}
+		if _, ok := k.GetLabels()[getTopicAppLabel("b")]; !ok {
+			t.Errorf("App label was not added")
+		}
+		if _, ok := k.GetAnnotations()[topicOrphanedAnnotation]; ok {
+			t.Errorf("Orphaned annotation was not removed")
+		}
+	})
+
+	t.Run("retain", func(t *testing.T) {
+		k := makeTopic()
+
+		del, update := applyTopicRetention(k, nil, crd.KafkaTopicRetentionConfig{}, now)
+
+		if del || !update {
+			t.Fatalf("Retained topic should be updated, not deleted")
+		}
+		if k.GetLabels()["env"] != "env" {
+			t.Errorf("Non app label was removed")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		del, _ := applyTopicRetention(makeTopic(), nil, crd.KafkaTopicRetentionConfig{Policy: "delete"}, now)
+
+		if !del {
+			t.Fatalf("Unreferenced topic was not deleted")
+		}
+	})
+
+	t.Run("deleteAfterDuration", func(t *testing.T) {
+		retention := crd.KafkaTopicRetentionConfig{
+			Policy:   "delete-after-duration",
+			Duration: &metav1.Duration{Duration: time.Hour},
+		}
+		k := makeTopic()
+
+		del, update := applyTopicRetention(k, nil, retention, now)
+		if del || !update {
+			t.Fatalf("Topic should be marked as orphaned, not deleted")
+		}
+		if _, ok := k.GetAnnotations()[topicOrphanedAnnotation]; !ok {
+			t.Fatalf("Orphaned annotation was not set")
+		}
+
+		del, _ = applyTopicRetention(k, nil, retention, now.Add(30*time.Minute))
+		if del {
+			t.Fatalf("Topic was deleted before the duration elapsed")
+		}
+
+		expiry, ok := getTopicExpiry(k, retention, now.Add(30*time.Minute))
+		if !ok || expiry <= 0 || expiry > 30*time.Minute {
+			t.Errorf("Wrong expiry %v; expected about 30m", expiry)
+		}
+
+		del, _ = applyTopicRetention(k, nil, retention, now.Add(2*time.Hour))
+		if !del {
+			t.Fatalf("Topic was not deleted after the duration elapsed")
+		}
+	})
+}
+
+func TestTopicAppLabel(t *testing.T) {
+	if label := getTopicAppLabel("app"); label != topicAppLabelPrefix+"app" {
+		t.Errorf("Wrong label %s; expected %sapp", label, topicAppLabelPrefix)
+	}
+
+	long := strings.Repeat("a", 100)
+	label := getTopicAppLabel(long)
+	name := strings.TrimPrefix(label, topicAppLabelPrefix)
+
+	if errs := validation.IsQualifiedName(label); len(errs) > 0 {
+		t.Errorf("Label for a long app name is invalid: %v", errs)
+	}
+	if label == getTopicAppLabel(strings.Repeat("a", 99)) {
+		t.Errorf("Labels for long app names with a common prefix were not distinct")
+	}
+	if len(name) != validation.DNS1123LabelMaxLength {
+		t.Errorf("Label name %s was not truncated to the maximum length", name)
+	}
+}
+
+func TestTopicConflicts(t *testing.T) {
+	env := getKafkaTestEnv()
+	env.Spec.Providers.Kafka.Cluster.Replicas = 3
+
+	requests := []topicRequest{{
+		App: "a",
+		Spec: crd.KafkaTopicSpec{
+			TopicName:  "topic",
+			Partitions: 3,
+			Config:     map[string]string{"retention.ms": "1000", "cleanup.policy": "delete"},
+		},
+	}, {
+		App: "b",
+		Spec: crd.KafkaTopicSpec{
+			TopicName:  "topic",
+			Partitions: 3,
+			Config:     map[string]string{"retention.ms": "2000"},
+		},
+	}}
+
+	k := &strimzi.KafkaTopic{Spec: &strimzi.KafkaTopicSpec{Config: map[string]string{}}}
+	conflicts, err := processTopicValues(k, &env, requests)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Spec.Config["retention.ms"] != "2000" {
+		t.Errorf("Wrong retention.ms %s; expected 2000", k.Spec.Config["retention.ms"])
+	}
+	if k.Spec.Config["cleanup.policy"] != "delete" {
+		t.Errorf("Config only requested by one app was not applied")
+	}
+	if k.Spec.Partitions != 3 {
+		t.Errorf("Wrong partitions %d; expected 3", k.Spec.Partitions)
+	}
+	if len(conflicts) != 1 || conflicts[0].Key != "retention.ms" {
+		t.Fatalf("Wrong conflicts %v; expected only retention.ms", conflicts)
+	}
+	if conflicts[0].Values["a"] != "1000" || conflicts[0].Values["b"] != "2000" {
+		t.Errorf("Wrong conflicting values %v", conflicts[0].Values)
+	}
+
+	app := crd.ClowdApp{}
+	setTopicConflictCondition(&app, map[string][]crd.KafkaTopicSettingConflict{"topic": conflicts})
+
+	if len(app.Status.Conditions) != 1 || app.Status.Conditions[0].Status != "True" {
+		t.Fatalf("Conflict condition was not set")
+	}
+
+	setTopicConflictStatus(&env, map[string][]topicRequest{"topic": requests})
+
+	if len(env.Status.KafkaTopicConflicts) != 1 {
+		t.Fatalf("Conflict was not reported in the environment status")
+	}
+	if env.Status.KafkaTopicConflicts[0].Config["retention.ms"] != "2000" {
+		t.Errorf("Resolved config was not reported in the environment status")
+	}
+}
+
+func TestTopicConfigConversion(t *testing.T) {
+	if _, err := getConversion("max.message.bytes"); err == nil {
+		t.Fatal("Unregistered key did not error")
+	}
+
+	clowder_config.LoadedConfig.Settings.KafkaTopicConfigConversions = map[string]string{
+		"max.message.bytes": "max",
+	}
+	defer func() {
+		clowder_config.LoadedConfig.Settings.KafkaTopicConfigConversions = nil
+	}()
+
+	f, err := getConversion("max.message.bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := f([]string{"100", "200"})
+	if out != "200" {
+		t.Errorf("Wrong converted value %s; expected 200", out)
+	}
+}
+
+func TestDerivedTopics(t *testing.T) {
+	app := crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns"},
+		Spec: crd.ClowdAppSpec{
+			KafkaTopics: []crd.KafkaTopicSpec{
+				{
+					TopicName:   "orders",
+					Access:      crd.KafkaTopicAccessConsume,
+					Partitions:  6,
+					DeadLetter:  true,
+					RetryTopics: []metav1.Duration{{Duration: time.Minute}, {Duration: time.Hour}},
+				},
+				{TopicName: "events"},
+			},
+		},
+	}
+
+	topics := getAppTopics(&crd.ClowdEnvironment{}, &app)
+
+	expected := []struct {
+		name      string
+		retention string
+	}{
+		{"orders", ""},
+		{"events", ""},
+		{"orders.dlq", "604800000"},
+		{"orders.retry.1", "86460000"},
+		{"orders.retry.2", "90000000"},
+	}
+
+	if len(topics) != len(expected) {
+		t.Fatalf("Wrong number of topics %d; expected %d", len(topics), len(expected))
+	}
+
+	for i, e := range expected {
+		topic := topics[i]
+		if topic.TopicName != e.name {
+			t.Errorf("Wrong topic name %s; expected %s", topic.TopicName, e.name)
+		}
+		if topic.Config["retention.ms"] != e.retention {
+			t.Errorf("Wrong retention for %s: %s; expected %s", e.name, topic.Config["retention.ms"], e.retention)
+		}
+	}
+
+	if topics[2].Access != crd.KafkaTopicAccessBoth || topics[2].Partitions != 6 {
+		t.Errorf("Dead-letter topic should have both access and the parent's partitions: %+v", topics[2])
+	}
+
+	acls, err := buildKafkaUserAcls(&app, getKafkaTestEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, acl := range acls {
+		if *acl.Resource.Name == "orders.dlq" && acl.Operation == strimzi.KafkaUserSpecAuthorizationAclsElemOperationWrite {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("No write ACL for dead-letter topic")
+	}
+}
+
+func TestTopicNamingTemplate(t *testing.T) {
+	env := getKafkaTestEnv()
+	topic := crd.KafkaTopicSpec{TopicName: "orders"}
+
+	if name, _ := getTopicName(topic, env, "ns"); name != "orders" {
+		t.Errorf("Wrong topic name %s; expected %s", name, "orders")
+	}
+
+	env.Spec.Providers.Kafka.TopicNamingTemplate = "{{.Namespace}}-{{.Env}}.{{.Topic}}"
+
+	if err := validateTopicNamingTemplate(&env); err != nil {
+		t.Fatal(err)
+	}
+
+	if name, _ := getTopicName(topic, env, "ns"); name != "ns-env.orders" {
+		t.Errorf("Wrong topic name %s; expected %s", name, "ns-env.orders")
+	}
+
+	if name, _ := getLocalTopicName("orders", &env, "ns"); name != "ns-env.orders" {
+		t.Errorf("Wrong local topic name %s; expected %s", name, "ns-env.orders")
+	}
+
+	for _, tmpl := range []string{"{{.Topic", "{{.Cluster}}", "{{if false}}x{{end}}", "{{.Topic}}/{{.Env}}"} {
+		env.Spec.Providers.Kafka.TopicNamingTemplate = tmpl
+		if err := validateTopicNamingTemplate(&env); err == nil {
+			t.Errorf("Expected template %s to be invalid", tmpl)
+		}
+	}
+
+	env.Spec.Providers.Kafka.TopicNamingTemplate = "{{.Env}}.{{.Topic}}"
+
+	if _, err := getLocalTopicName("Orders", &env, "ns"); err != nil {
+		t.Errorf("Mixed case topic name should be valid outside of operator mode: %v", err)
+	}
+
+	env.Spec.Providers.Kafka.Mode = "operator"
+
+	if _, err := getTopicName(crd.KafkaTopicSpec{TopicName: "Orders"}, env, "ns"); err == nil {
+		t.Error("Expected an error naming a KafkaTopic with an invalid rendered name")
+	}
+}
+
+func TestLoggingTopic(t *testing.T) {
+	app := crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns"},
+		Spec: crd.ClowdAppSpec{
+			KafkaTopics: []crd.KafkaTopicSpec{{TopicName: "orders"}},
+		},
+	}
+
+	env := crd.ClowdEnvironment{}
+
+	if topics := getRequestedTopics(&env, &app); len(topics) != 1 {
+		t.Fatalf("Wrong number of topics %d; expected 1", len(topics))
+	}
+
+	env.Spec.Providers.Logging.Mode = "kafka"
+	env.Spec.Providers.Logging.Kafka.Partitions = 12
+
+	topics := getRequestedTopics(&env, &app)
+	if len(topics) != 2 {
+		t.Fatalf("Wrong number of topics %d; expected 2", len(topics))
+	}
+
+	logging := topics[1]
+	if logging.TopicName != crd.DefaultLoggingTopicName || logging.Partitions != 12 {
+		t.Errorf("Wrong logging topic %s with %d partitions", logging.TopicName, logging.Partitions)
+	}
+	if logging.Access.CanConsume() {
+		t.Errorf("Logging topic should be produce only")
+	}
+
+	if len(app.Spec.KafkaTopics) != 1 {
+		t.Errorf("App's topics were modified")
+	}
+
+	app.Spec.Logging = &crd.AppLoggingSpec{DisableKafka: true}
+	if topics := getRequestedTopics(&env, &app); len(topics) != 1 {
+		t.Errorf("Wrong number of topics %d for an app opting out; expected 1", len(topics))
+	}
+
+	noTopics := crd.ClowdApp{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"}}
+	if topics := getRequestedTopics(&env, &noTopics); len(topics) != 0 {
+		t.Errorf("Wrong number of topics %d for an app without topics; expected 0", len(topics))
+	}
+}
@@ -24,7 +24,14 @@ func NewAppInterfaceLogging(p *providers.Provider) (providers.ClowderProvider, e
 
 func (a *appInterfaceLoggingProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
 	c.Logging = config.LoggingConfig{}
-	return setCloudwatchSecret(app.Namespace, &a.Provider, &c.Logging)
+	if err := setCloudwatchSecret(app.Namespace, &a.Provider, &c.Logging); err != nil {
+		return err
+	}
+
+	setLogSettings(a.Env, app, &c.Logging)
+	setKafkaLoggingCondition(a.Env, app)
+
+	return nil
 }
 
 func setCloudwatchSecret(ns string, p *providers.Provider, c *config.LoggingConfig) error {
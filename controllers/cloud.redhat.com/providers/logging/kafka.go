@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"fmt"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	core "k8s.io/api/core/v1"
+)
+
+type kafkaLoggingProvider struct {
+	providers.Provider
+}
+
+// NewKafkaLogging returns a new kafka logging provider object.
+func NewKafkaLogging(p *providers.Provider) (providers.ClowderProvider, error) {
+	return &kafkaLoggingProvider{Provider: *p}, nil
+}
+
+// validateKafkaLogging checks that the environment's kafka provider can provide the logging topic.
+func validateKafkaLogging(env *crd.ClowdEnvironment) error {
+	switch env.Spec.Providers.Kafka.Mode {
+	case "none", "":
+		return errors.New("kafka logging mode requires a kafka provider mode other than none")
+	}
+	return nil
+}
+
+// Provide passes the app the name of the topic to produce its logs to. The topic is requested for
+// the apps using kafka logging by the kafka provider, which has already run, so its actual name is
+// taken from the app's kafka configuration. Other apps are given no logging configuration, which is
+// reported by the KafkaLoggingDisabled condition.
+func (k *kafkaLoggingProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
+	if !k.Env.UsesKafkaLogging(app) {
+		none := noneLoggingProvider{Provider: k.Provider}
+		return none.Provide(app, c)
+	}
+
+	topic, err := getLoggingTopic(k.Env, c.Kafka)
+	if err != nil {
+		return err
+	}
+
+	c.Logging = config.LoggingConfig{
+		Kafka: &config.LoggingKafkaConfig{Topic: topic},
+		Type:  "kafka",
+	}
+
+	setLogSettings(k.Env, app, &c.Logging)
+	setKafkaLoggingCondition(k.Env, app)
+
+	return nil
+}
+
+// setKafkaLoggingCondition reports, on the app, that kafka logging mode has left it without any
+// logging configuration, so that the missing logs are not a surprise. Every logging mode calls
+// this, so that the condition is removed once it no longer applies.
+func setKafkaLoggingCondition(env *crd.ClowdEnvironment, app *crd.ClowdApp) {
+	if env.Spec.Providers.Logging.Mode != "kafka" || env.UsesKafkaLogging(app) {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.KafkaLoggingDisabled)
+		return
+	}
+
+	condition := crd.ClowdCondition{
+		Type:    crd.KafkaLoggingDisabled,
+		Status:  core.ConditionTrue,
+		Reason:  "NoKafkaTopics",
+		Message: "app requests no kafkaTopics, so it is given no logging configuration",
+	}
+	if len(app.Spec.KafkaTopics) > 0 {
+		condition.Reason = "DisabledByApp"
+		condition.Message = "app sets disableKafka, so it is given no logging configuration"
+	}
+	crd.UpdateClowdAppCondition(&app.Status, &condition)
+}
+
+func getLoggingTopic(env *crd.ClowdEnvironment, kafkaConfig *config.KafkaConfig) (string, error) {
+	requestedName := env.GetLoggingTopicName()
+
+	if kafkaConfig == nil {
+		return "", errors.New(fmt.Sprintf(
+			"logging topic %q -- kafka logging requires the kafka provider to configure the app", requestedName,
+		))
+	}
+
+	for _, topic := range kafkaConfig.Topics {
+		if topic.RequestedName == requestedName {
+			return topic.Name, nil
+		}
+	}
+
+	return "", errors.New(fmt.Sprintf("logging topic %q -- topic was not provided by kafka", requestedName))
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLoggingTopic(t *testing.T) {
+	assert := assert.New(t)
+
+	env := &crd.ClowdEnvironment{}
+	kafkaConfig := &config.KafkaConfig{
+		Topics: []config.TopicConfig{
+			{Name: "env-ns-orders", RequestedName: "orders"},
+			{Name: "env-ns-platform.logging", RequestedName: "platform.logging"},
+		},
+	}
+
+	topic, err := getLoggingTopic(env, kafkaConfig)
+	assert.NoError(err)
+	assert.Equal("env-ns-platform.logging", topic)
+
+	env.Spec.Providers.Logging.Kafka.TopicName = "app-logs"
+	_, err = getLoggingTopic(env, kafkaConfig)
+	assert.Error(err)
+
+	_, err = getLoggingTopic(env, nil)
+	assert.Error(err)
+}
+
+func TestValidateKafkaLogging(t *testing.T) {
+	assert := assert.New(t)
+
+	env := &crd.ClowdEnvironment{}
+	assert.Error(validateKafkaLogging(env))
+
+	env.Spec.Providers.Kafka.Mode = "none"
+	assert.Error(validateKafkaLogging(env))
+
+	env.Spec.Providers.Kafka.Mode = "app-interface"
+	assert.NoError(validateKafkaLogging(env))
+}
+
+func TestKafkaLoggingWithoutTopics(t *testing.T) {
+	assert := assert.New(t)
+
+	env := &crd.ClowdEnvironment{}
+	env.Spec.Providers.Logging.Mode = "kafka"
+	env.Spec.Providers.Kafka.Mode = "operator"
+
+	k := &kafkaLoggingProvider{Provider: providers.Provider{Env: env}}
+
+	noTopics := &crd.ClowdApp{}
+	c := config.AppConfig{}
+	assert.NoError(k.Provide(noTopics, &c))
+	assert.Equal("null", c.Logging.Type)
+	assert.Nil(c.Logging.Kafka)
+	assertLoggingCondition(t, noTopics, "NoKafkaTopics")
+
+	app := &crd.ClowdApp{Spec: crd.ClowdAppSpec{
+		KafkaTopics: []crd.KafkaTopicSpec{{TopicName: "orders"}},
+		Logging:     &crd.AppLoggingSpec{DisableKafka: true},
+	}}
+	c = config.AppConfig{Kafka: &config.KafkaConfig{}}
+	assert.NoError(k.Provide(app, &c))
+	assert.Equal("null", c.Logging.Type)
+	assertLoggingCondition(t, app, "DisabledByApp")
+
+	app.Spec.Logging = nil
+	c = config.AppConfig{Kafka: &config.KafkaConfig{Topics: []config.TopicConfig{
+		{Name: "env-ns-platform.logging", RequestedName: "platform.logging"},
+	}}}
+	assert.NoError(k.Provide(app, &c))
+	assert.Equal("kafka", c.Logging.Type)
+	assert.Empty(app.Status.Conditions)
+}
+
+func assertLoggingCondition(t *testing.T, app *crd.ClowdApp, reason string) {
+	if len(app.Status.Conditions) != 1 {
+		t.Fatalf("Wrong conditions %v; expected a single %s condition", app.Status.Conditions, crd.KafkaLoggingDisabled)
+	}
+
+	condition := app.Status.Conditions[0]
+	assert.Equal(t, crd.KafkaLoggingDisabled, condition.Type)
+	assert.Equal(t, reason, condition.Reason)
+}
@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"fmt"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	obj "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/object"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// LokiDeployment is the ident refering to the local Loki deployment object.
+var LokiDeployment = providers.NewSingleResourceIdent(ProvName, "loki_deployment", &apps.Deployment{})
+
+// LokiService is the ident refering to the local Loki service object.
+var LokiService = providers.NewSingleResourceIdent(ProvName, "loki_service", &core.Service{})
+
+const lokiPort = 3100
+
+type lokiLoggingProvider struct {
+	providers.Provider
+	Config config.LokiConfig
+}
+
+// NewLokiLogging returns a new loki logging provider object.
+func NewLokiLogging(p *providers.Provider) (providers.ClowderProvider, error) {
+	objList := []providers.ResourceIdent{
+		LokiDeployment,
+		LokiService,
+	}
+
+	if err := providers.CachedMakeComponent(p.Cache, objList, p.Env, "loki", makeLocalLoki, false, p.Env.IsNodePort()); err != nil {
+		return nil, err
+	}
+
+	nn := providers.GetNamespacedName(p.Env, "loki")
+
+	provider := lokiLoggingProvider{
+		Provider: *p,
+		Config: config.LokiConfig{
+			Url: fmt.Sprintf("http://%s.%s.svc:%d", nn.Name, nn.Namespace, lokiPort),
+		},
+	}
+
+	return &provider, nil
+}
+
+func (l *lokiLoggingProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
+	lokiConfig := l.Config
+
+	c.Logging = config.LoggingConfig{
+		Loki: &lokiConfig,
+		Type: "loki",
+	}
+
+	setLogSettings(l.Env, app, &c.Logging)
+	setKafkaLoggingCondition(l.Env, app)
+
+	return nil
+}
+
+func makeLocalLoki(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool) {
+	nn := providers.GetNamespacedName(o, "loki")
+
+	dd := objMap[LokiDeployment].(*apps.Deployment)
+	svc := objMap[LokiService].(*core.Service)
+
+	labels := o.GetLabels()
+	labels["env-app"] = nn.Name
+
+	labeler := utils.MakeLabeler(nn, labels, o)
+
+	labeler(dd)
+
+	replicas := int32(1)
+
+	dd.Spec.Replicas = &replicas
+	dd.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	dd.Spec.Template.ObjectMeta.Labels = labels
+
+	probeHandler := core.Handler{
+		HTTPGet: &core.HTTPGetAction{
+			Path: "/ready",
+			Port: intstr.FromInt(lokiPort),
+		},
+	}
+
+	livenessProbe := core.Probe{
+		Handler:             probeHandler,
+		InitialDelaySeconds: 45,
+		TimeoutSeconds:      2,
+	}
+	readinessProbe := core.Probe{
+		Handler:             probeHandler,
+		InitialDelaySeconds: 15,
+		TimeoutSeconds:      2,
+	}
+
+	// the local config of the loki image keeps its chunks and index on the filesystem under /loki
+	dd.Spec.Template.Spec.Volumes = []core.Volume{{
+		Name: "loki-data",
+		VolumeSource: core.VolumeSource{
+			EmptyDir: &core.EmptyDirVolumeSource{},
+		},
+	}}
+
+	dd.Spec.Template.Spec.Containers = []core.Container{{
+		Name:  nn.Name,
+		Image: "docker.io/grafana/loki:2.4.2",
+		Args: []string{
+			"-config.file=/etc/loki/local-config.yaml",
+		},
+		Ports: []core.ContainerPort{{
+			Name:          "loki",
+			ContainerPort: lokiPort,
+		}},
+		LivenessProbe:  &livenessProbe,
+		ReadinessProbe: &readinessProbe,
+		VolumeMounts: []core.VolumeMount{{
+			Name:      "loki-data",
+			MountPath: "/loki",
+		}},
+	}}
+
+	servicePorts := []core.ServicePort{{
+		Name:     "loki",
+		Port:     lokiPort,
+		Protocol: "TCP",
+	}}
+
+	utils.MakeService(svc, nn, labels, servicePorts, o, nodePort)
+}
@@ -29,5 +29,8 @@ func (a *noneLoggingProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) er
 		Type: "null",
 	}
 
+	setLogSettings(a.Env, app, &c.Logging)
+	setKafkaLoggingCondition(a.Env, app)
+
 	return nil
 }
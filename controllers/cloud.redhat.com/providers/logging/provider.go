@@ -7,12 +7,24 @@ import (
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
 )
 
+// ProvName is the name/ident of the provider
+var ProvName = "logging"
+
 // GetLogging returns the correct logging provider based on the environment.
 func GetLogging(c *providers.Provider) (providers.ClowderProvider, error) {
 	logMode := c.Env.Spec.Providers.Logging.Mode
 	switch logMode {
 	case "app-interface":
 		return NewAppInterfaceLogging(c)
+	case "loki":
+		return NewLokiLogging(c)
+	case "splunk":
+		return NewSplunkLogging(c)
+	case "kafka":
+		if err := validateKafkaLogging(c.Env); err != nil {
+			return nil, err
+		}
+		return NewKafkaLogging(c)
 	case "none", "null", "":
 		return NewNoneLogging(c)
 	default:
@@ -22,5 +34,6 @@ func GetLogging(c *providers.Provider) (providers.ClowderProvider, error) {
 }
 
 func init() {
-	providers.ProvidersRegistration.Register(GetLogging, 5, "logging")
+	// the logging provider runs after the kafka provider, which creates the topic used in kafka mode
+	providers.ProvidersRegistration.Register(GetLogging, 7, ProvName)
 }
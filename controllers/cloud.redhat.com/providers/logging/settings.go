@@ -0,0 +1,52 @@
+package logging
+
+import (
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+)
+
+// mergeLogSettings returns the base settings with any set in the overrides replacing them.
+func mergeLogSettings(base crd.LogSettings, overrides crd.LogSettings) crd.LogSettings {
+	if overrides.Level != "" {
+		base.Level = overrides.Level
+	}
+	if overrides.Format != "" {
+		base.Format = overrides.Format
+	}
+	return base
+}
+
+func getLogSetting(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return providers.StrPtr(value)
+}
+
+// setLogSettings adds the level and format the app should log with to its logging configuration.
+// The app's settings override the environment's defaults, and are in turn overridden by those of
+// its deployments, which are only listed when they have settings of their own.
+func setLogSettings(env *crd.ClowdEnvironment, app *crd.ClowdApp, c *config.LoggingConfig) {
+	settings := env.Spec.Providers.Logging.LogSettings
+	if app.Spec.Logging != nil {
+		settings = mergeLogSettings(settings, app.Spec.Logging.LogSettings)
+	}
+
+	c.Level = getLogSetting(string(settings.Level))
+	c.Format = getLogSetting(string(settings.Format))
+
+	for _, deployment := range app.Spec.Deployments {
+		if deployment.Logging == nil {
+			continue
+		}
+
+		deploymentSettings := mergeLogSettings(settings, *deployment.Logging)
+
+		c.Deployments = append(c.Deployments, config.DeploymentLoggingConfig{
+			Name:   deployment.Name,
+			Level:  getLogSetting(string(deploymentSettings.Level)),
+			Format: getLogSetting(string(deploymentSettings.Format)),
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLogSettings(t *testing.T) {
+	assert := assert.New(t)
+
+	env := &crd.ClowdEnvironment{}
+	env.Spec.Providers.Logging.LogSettings = crd.LogSettings{Level: "info", Format: "json"}
+
+	app := &crd.ClowdApp{}
+	app.Spec.Deployments = []crd.Deployment{
+		{Name: "api"},
+		{Name: "worker", Logging: &crd.LogSettings{Level: "debug"}},
+	}
+
+	c := config.LoggingConfig{}
+	setLogSettings(env, app, &c)
+	assert.Equal("info", *c.Level)
+	assert.Equal("json", *c.Format)
+	if assert.Len(c.Deployments, 1) {
+		assert.Equal("worker", c.Deployments[0].Name)
+		assert.Equal("debug", *c.Deployments[0].Level)
+		assert.Equal("json", *c.Deployments[0].Format)
+	}
+
+	app.Spec.Logging = &crd.AppLoggingSpec{LogSettings: crd.LogSettings{Level: "warning", Format: "text"}}
+
+	c = config.LoggingConfig{}
+	setLogSettings(env, app, &c)
+	assert.Equal("warning", *c.Level)
+	assert.Equal("text", *c.Format)
+	assert.Equal("text", *c.Deployments[0].Format)
+
+	c = config.LoggingConfig{}
+	setLogSettings(&crd.ClowdEnvironment{}, &crd.ClowdApp{}, &c)
+	assert.Nil(c.Level)
+	assert.Nil(c.Format)
+	assert.Len(c.Deployments, 0)
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"fmt"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type splunkLoggingProvider struct {
+	providers.Provider
+	Config config.SplunkConfig
+}
+
+// NewSplunkLogging returns a new splunk logging provider object.
+func NewSplunkLogging(p *providers.Provider) (providers.ClowderProvider, error) {
+	secretRef := types.NamespacedName{
+		Name:      p.Env.Spec.Providers.Logging.Splunk.SecretRef.Name,
+		Namespace: p.Env.Spec.Providers.Logging.Splunk.SecretRef.Namespace,
+	}
+
+	nullName := types.NamespacedName{}
+
+	if secretRef == nullName {
+		return nil, errors.New("no secret ref defined for splunk logging")
+	}
+
+	secret := &core.Secret{}
+
+	if err := p.Client.Get(p.Ctx, secretRef, secret); err != nil {
+		return nil, errors.Wrap("Failed to fetch splunk secret", err)
+	}
+
+	splunkConfig, err := parseSplunkSecret(secret.Data)
+	if err != nil {
+		return nil, errors.Wrap("invalid splunk secret", err)
+	}
+
+	return &splunkLoggingProvider{Provider: *p, Config: *splunkConfig}, nil
+}
+
+// parseSplunkSecret reads the HEC URL and token, and the optional index, from the data of the
+// secret referenced by the env.
+func parseSplunkSecret(data map[string][]byte) (*config.SplunkConfig, error) {
+	for _, key := range []string{"url", "token"} {
+		if len(data[key]) == 0 {
+			return nil, errors.New(fmt.Sprintf("no %s in splunk secret", key))
+		}
+	}
+
+	splunkConfig := &config.SplunkConfig{
+		Url:   string(data["url"]),
+		Token: string(data["token"]),
+	}
+
+	if index, ok := data["index"]; ok {
+		splunkConfig.Index = providers.StrPtr(string(index))
+	}
+
+	return splunkConfig, nil
+}
+
+func (s *splunkLoggingProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
+	splunkConfig := s.Config
+
+	c.Logging = config.LoggingConfig{
+		Splunk: &splunkConfig,
+		Type:   "splunk",
+	}
+
+	setLogSettings(s.Env, app, &c.Logging)
+	setKafkaLoggingCondition(s.Env, app)
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSplunkSecret(t *testing.T) {
+	assert := assert.New(t)
+
+	splunkConfig, err := parseSplunkSecret(map[string][]byte{
+		"url":   []byte("https://hec.example.com:8088"),
+		"token": []byte("token"),
+		"index": []byte("insights"),
+	})
+	assert.NoError(err)
+	assert.Equal("https://hec.example.com:8088", splunkConfig.Url)
+	assert.Equal("token", splunkConfig.Token)
+	assert.Equal("insights", *splunkConfig.Index)
+
+	splunkConfig, err = parseSplunkSecret(map[string][]byte{
+		"url":   []byte("https://hec.example.com:8088"),
+		"token": []byte("token"),
+	})
+	assert.NoError(err)
+	assert.Nil(splunkConfig.Index)
+
+	_, err = parseSplunkSecret(map[string][]byte{"url": []byte("https://hec.example.com:8088")})
+	assert.Error(err)
+}
@@ -52,6 +52,12 @@ func NewMetricsProvider(p *providers.Provider) (providers.ClowderProvider, error
 			"prometheus": p.Env.Name,
 		},
 	}
+	promObj.Spec.PodMonitorSelector = &v1.LabelSelector{
+		MatchLabels: map[string]string{
+			"prometheus": p.Env.Name,
+		},
+	}
+	promObj.Spec.PodMonitorNamespaceSelector = &v1.LabelSelector{}
 	promObj.Spec.ServiceAccountName = "prometheus"
 
 	labeler := utils.GetCustomLabeler(map[string]string{"env": p.Env.Name}, nn, p.Env)
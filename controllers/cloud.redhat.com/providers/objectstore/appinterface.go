@@ -25,42 +25,95 @@ func NewAppInterfaceObjectstore(p *providers.Provider) (providers.ClowderProvide
 }
 
 func (a *appInterfaceObjectstoreProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
-	if len(app.Spec.ObjectStore) == 0 {
+	requestedBuckets := []string{}
+	for _, bucket := range app.GetObjectStoreBuckets() {
+		requestedBuckets = append(requestedBuckets, bucket.Name)
+	}
+
+	if len(requestedBuckets) == 0 && len(app.Spec.SharedBuckets) == 0 {
 		return nil
 	}
 
+	sharedBuckets, err := getSharedBuckets(&a.Provider, app)
+	if err != nil {
+		return err
+	}
+
+	objStoreConfig, err := a.getBucketConfig(requestedBuckets, app.Namespace)
+
+	if err != nil {
+		return err
+	}
+
+	for _, shared := range sharedBuckets {
+		if shared.ReadOnly {
+			return newReadOnlyUnsupportedError("app-interface", shared)
+		}
+
+		// shared buckets are found in the secrets of the app that requested them
+		sharedConfig, err := a.getBucketConfig([]string{shared.Bucket}, shared.Owner.Namespace)
+
+		if err != nil {
+			return err
+		}
+
+		if objStoreConfig.Hostname == "" {
+			objStoreConfig.Hostname = sharedConfig.Hostname
+		}
+
+		objStoreConfig.Buckets = append(objStoreConfig.Buckets, sharedConfig.Buckets...)
+	}
+
+	c.ObjectStore = objStoreConfig
+	return nil
+}
+
+// getBucketConfig returns the config for the given buckets, found in the secrets of the namespace.
+func (a *appInterfaceObjectstoreProvider) getBucketConfig(
+	requestedBuckets []string, namespace string,
+) (*config.ObjectStoreConfig, error) {
 	secrets := core.SecretList{}
-	err := a.Client.List(a.Ctx, &secrets, client.InNamespace(app.Namespace))
+	err := a.Client.List(a.Ctx, &secrets, client.InNamespace(namespace))
 
 	if err != nil {
-		msg := fmt.Sprintf("Failed to list secrets in %s", app.Namespace)
-		return errors.Wrap(msg, err)
+		msg := fmt.Sprintf("Failed to list secrets in %s", namespace)
+		return nil, errors.Wrap(msg, err)
 	}
 
 	objStoreConfig, err := genObjStoreConfig(secrets.Items)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = resolveBucketDeps(app.Spec.ObjectStore, objStoreConfig)
+	err = resolveBucketDeps(requestedBuckets, objStoreConfig, a.Env, namespace)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	c.ObjectStore = objStoreConfig
-	return nil
+	return objStoreConfig, nil
 }
 
-func resolveBucketDeps(requestedBuckets []string, c *config.ObjectStoreConfig) error {
+// resolveBucketDeps finds the bucket in app-interface for each requested bucket. Without a bucket
+// naming template, the first bucket whose name starts with the requested name is used. With a
+// template, the bucket must have exactly the templated name.
+func resolveBucketDeps(
+	requestedBuckets []string, c *config.ObjectStoreConfig, env *crd.ClowdEnvironment, namespace string,
+) error {
 	buckets := []config.ObjectStoreBucket{}
 	missing := []string{}
+	templated := env.Spec.Providers.ObjectStore.BucketNamingTemplate != ""
 
 	for _, requestedBucket := range requestedBuckets {
+		name, err := getBucketName(env, requestedBucket, namespace)
+		if err != nil {
+			return err
+		}
+
 		found := false
 		for _, bucket := range c.Buckets {
-			if strings.HasPrefix(bucket.Name, requestedBucket) {
+			if bucket.Name == name || (!templated && strings.HasPrefix(bucket.Name, requestedBucket)) {
 				found = true
 				bucket.RequestedName = requestedBucket
 				buckets = append(buckets, bucket)
@@ -69,7 +122,7 @@ func resolveBucketDeps(requestedBuckets []string, c *config.ObjectStoreConfig) e
 		}
 
 		if !found {
-			missing = append(missing, requestedBucket)
+			missing = append(missing, name)
 		}
 	}
 
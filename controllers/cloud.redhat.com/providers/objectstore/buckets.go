@@ -0,0 +1,294 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/minio/minio-go/v7/pkg/tags"
+)
+
+// bucketEnvTag records the environment a bucket was created for.
+const bucketEnvTag = "clowder-env"
+
+// bucketDeletionPolicyTag records the deletion policy applied to a bucket once no app requests it.
+const bucketDeletionPolicyTag = "clowder-deletion-policy"
+
+// bucketFixturesPendingTag marks a bucket whose fixtures job has not yet been created.
+const bucketFixturesPendingTag = "clowder-fixtures-pending"
+
+// bucketOrphanedTag records when a bucket was first found to be requested by no app.
+const bucketOrphanedTag = "clowder-orphaned-at"
+
+// bucketOrphanGracePeriod is how long a bucket must go unrequested before it is deleted, so that a
+// momentarily stale list of apps, or an app being recreated, does not lose its bucket.
+const bucketOrphanGracePeriod = time.Hour
+
+const bucketConfigureErrorMsg = "failed to configure bucket"
+const bucketDeleteErrorMsg = "failed to delete bucket"
+
+// expirationRuleID identifies the lifecycle rule Clowder manages on a bucket.
+const expirationRuleID = "clowder-expiration"
+
+func (h *minioHandler) SetTags(ctx context.Context, bucketName string, tagMap map[string]string) error {
+	t, err := tags.MapToBucketTags(tagMap)
+	if err != nil {
+		return err
+	}
+	return h.Client.SetBucketTagging(ctx, bucketName, t)
+}
+
+func (h *minioHandler) SetLifecycle(ctx context.Context, bucketName string, expirationDays *int32) error {
+	config := lifecycle.NewConfiguration()
+	if expirationDays != nil {
+		config.Rules = []lifecycle.Rule{{
+			ID:     expirationRuleID,
+			Status: "Enabled",
+			Expiration: lifecycle.Expiration{
+				Days: lifecycle.ExpirationDays(*expirationDays),
+			},
+		}}
+	}
+	// an empty configuration removes the bucket's lifecycle rules
+	return h.Client.SetBucketLifecycle(ctx, bucketName, config)
+}
+
+func (h *minioHandler) SetVersioning(ctx context.Context, bucketName string, enabled bool) error {
+	current, err := h.Client.GetBucketVersioning(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case enabled && current.Status != "Enabled":
+		return h.Client.EnableVersioning(ctx, bucketName)
+	case !enabled && current.Status == "Enabled":
+		return h.Client.SuspendVersioning(ctx, bucketName)
+	}
+
+	return nil
+}
+
+func (h *minioHandler) SetPublicRead(ctx context.Context, bucketName string, public bool) error {
+	if !public {
+		// an empty policy removes the bucket's policy
+		return h.Client.SetBucketPolicy(ctx, bucketName, "")
+	}
+
+	policy, err := makePublicReadPolicy(bucketName)
+	if err != nil {
+		return err
+	}
+	return h.Client.SetBucketPolicy(ctx, bucketName, string(policy))
+}
+
+func (h *minioHandler) ListTagged(ctx context.Context) (map[string]map[string]string, error) {
+	buckets, err := h.Client.ListBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := map[string]map[string]string{}
+	for _, bucket := range buckets {
+		t, err := h.GetTags(ctx, bucket.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(t) > 0 {
+			tagged[bucket.Name] = t
+		}
+	}
+
+	return tagged, nil
+}
+
+func (h *minioHandler) GetTags(ctx context.Context, bucketName string) (map[string]string, error) {
+	t, err := h.Client.GetBucketTagging(ctx, bucketName)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchTagSet" {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	return t.ToMap(), nil
+}
+
+func (h *minioHandler) Remove(ctx context.Context, bucketName string) error {
+	objects := h.Client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
+		Recursive:    true,
+		WithVersions: true,
+	})
+
+	var err error
+	for removeErr := range h.Client.RemoveObjects(ctx, bucketName, objects, minio.RemoveObjectsOptions{}) {
+		if err == nil {
+			err = removeErr.Err
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return h.Client.RemoveBucket(ctx, bucketName)
+}
+
+type publicReadPolicyStatement struct {
+	Effect    string              `json:"Effect"`
+	Principal map[string][]string `json:"Principal"`
+	Action    []string            `json:"Action"`
+	Resource  []string            `json:"Resource"`
+}
+
+// makePublicReadPolicy returns a bucket policy allowing anyone to read the bucket's objects.
+func makePublicReadPolicy(bucketName string) ([]byte, error) {
+	return json.Marshal(struct {
+		Version   string                      `json:"Version"`
+		Statement []publicReadPolicyStatement `json:"Statement"`
+	}{
+		Version: "2012-10-17",
+		Statement: []publicReadPolicyStatement{{
+			Effect:    "Allow",
+			Principal: map[string][]string{"AWS": {"*"}},
+			Action:    []string{"s3:GetObject"},
+			Resource:  []string{fmt.Sprintf("arn:aws:s3:::%s/*", bucketName)},
+		}},
+	})
+}
+
+// getBucketRequests returns the buckets requested by the apps in the environment, keyed by their
+// actual name. Apps which are being deleted are not counted.
+func getBucketRequests(env *crd.ClowdEnvironment, appList *crd.ClowdAppList) (map[string][]crd.ObjectStoreBucketSpec, error) {
+	requests := map[string][]crd.ObjectStoreBucketSpec{}
+
+	for i := range appList.Items {
+		app := &appList.Items[i]
+		if app.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		for _, bucket := range app.GetObjectStoreBuckets() {
+			name, err := getBucketName(env, bucket.Name, app.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			requests[name] = append(requests[name], bucket)
+		}
+	}
+
+	return requests, nil
+}
+
+// getDeletionPolicy returns the deletion policy of a bucket requested with the given specs. The
+// bucket is only deleted if every request asks for it to be.
+func getDeletionPolicy(specs []crd.ObjectStoreBucketSpec) crd.BucketDeletionPolicy {
+	if len(specs) == 0 {
+		return "retain"
+	}
+
+	for _, spec := range specs {
+		if spec.DeletionPolicy != "delete" {
+			return "retain"
+		}
+	}
+
+	return "delete"
+}
+
+// configureBucket applies the settings requested by an app to a bucket, and tags the bucket with
+// the environment, the deletion policy of all the apps requesting it, and whether its fixtures are
+// yet to be loaded.
+func (m *minioProvider) configureBucket(name string, spec crd.ObjectStoreBucketSpec, fixturesPending bool) error {
+	deletionPolicy := getDeletionPolicy(append([]crd.ObjectStoreBucketSpec{spec}, m.BucketRequests[name]...))
+
+	bucketTags := map[string]string{
+		bucketEnvTag:            m.Env.Name,
+		bucketDeletionPolicyTag: string(deletionPolicy),
+	}
+	if fixturesPending {
+		bucketTags[bucketFixturesPendingTag] = "true"
+	}
+
+	if err := m.BucketHandler.SetTags(m.Ctx, name, bucketTags); err != nil {
+		return newBucketError(bucketConfigureErrorMsg, name, err)
+	}
+
+	if err := m.BucketHandler.SetLifecycle(m.Ctx, name, spec.ExpirationDays); err != nil {
+		return newBucketError(bucketConfigureErrorMsg, name, err)
+	}
+
+	if err := m.BucketHandler.SetVersioning(m.Ctx, name, spec.Versioning); err != nil {
+		return newBucketError(bucketConfigureErrorMsg, name, err)
+	}
+
+	if err := m.BucketHandler.SetPublicRead(m.Ctx, name, spec.PublicRead); err != nil {
+		return newBucketError(bucketConfigureErrorMsg, name, err)
+	}
+
+	return nil
+}
+
+// gcBuckets deletes the environment's buckets which no app has requested for the grace period,
+// if their deletion policy allows it. Unrequested buckets are tagged with the time they were first
+// found to be so, and the tag is removed if they are requested again. The time until the next
+// orphaned bucket is due to be deleted is returned, or zero if none are.
+func gcBuckets(
+	ctx context.Context,
+	env *crd.ClowdEnvironment,
+	handler bucketHandler,
+	requests map[string][]crd.ObjectStoreBucketSpec,
+	now time.Time,
+) (time.Duration, error) {
+	tagged, err := handler.ListTagged(ctx)
+	if err != nil {
+		return 0, errors.Wrap("Bucket cleanup failed: Error listing buckets", err)
+	}
+
+	var nextExpiry time.Duration
+
+	for name, bucketTags := range tagged {
+		if bucketTags[bucketEnvTag] != env.Name {
+			continue
+		}
+
+		_, orphaned := bucketTags[bucketOrphanedTag]
+
+		if _, ok := requests[name]; ok || bucketTags[bucketDeletionPolicyTag] != "delete" {
+			if orphaned {
+				delete(bucketTags, bucketOrphanedTag)
+				if err := handler.SetTags(ctx, name, bucketTags); err != nil {
+					return 0, newBucketError(bucketConfigureErrorMsg, name, err)
+				}
+			}
+			continue
+		}
+
+		orphanedAt, err := time.Parse(time.RFC3339, bucketTags[bucketOrphanedTag])
+		if err != nil {
+			orphanedAt = now
+			bucketTags[bucketOrphanedTag] = now.UTC().Format(time.RFC3339)
+			if err := handler.SetTags(ctx, name, bucketTags); err != nil {
+				return 0, newBucketError(bucketConfigureErrorMsg, name, err)
+			}
+		}
+
+		expiry := orphanedAt.Add(bucketOrphanGracePeriod).Sub(now)
+		if expiry > 0 {
+			if nextExpiry == 0 || expiry < nextExpiry {
+				nextExpiry = expiry
+			}
+			continue
+		}
+
+		if err := handler.Remove(ctx, name); err != nil {
+			return 0, newBucketError(bucketDeleteErrorMsg, name, err)
+		}
+	}
+
+	return nextExpiry, nil
+}
@@ -0,0 +1,58 @@
+package objectstore
+
+import (
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBucketRequests(t *testing.T) {
+	now := metav1.Now()
+	env := getTemplatedEnv("{{.Namespace}}-{{.Bucket}}")
+
+	appList := &crd.ClowdAppList{Items: []crd.ClowdApp{{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns1"},
+		Spec: crd.ClowdAppSpec{
+			ObjectStore:        []string{"uploads", "reports"},
+			ObjectStoreBuckets: []crd.ObjectStoreBucketSpec{{Name: "uploads", DeletionPolicy: "delete"}},
+		},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns2", DeletionTimestamp: &now},
+		Spec:       crd.ClowdAppSpec{ObjectStore: []string{"uploads"}},
+	}}}
+
+	requests, err := getBucketRequests(env, appList)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 || len(requests["ns1-uploads"]) != 1 || len(requests["ns1-reports"]) != 1 {
+		t.Fatalf("Wrong bucket requests %+v", requests)
+	}
+	if requests["ns1-uploads"][0].DeletionPolicy != "delete" {
+		t.Errorf("objectStoreBuckets settings should take precedence over objectStore names")
+	}
+}
+
+func TestBucketDeletionPolicy(t *testing.T) {
+	tests := []struct {
+		policies []crd.BucketDeletionPolicy
+		want     crd.BucketDeletionPolicy
+	}{
+		{[]crd.BucketDeletionPolicy{}, "retain"},
+		{[]crd.BucketDeletionPolicy{"delete"}, "delete"},
+		{[]crd.BucketDeletionPolicy{"delete", ""}, "retain"},
+		{[]crd.BucketDeletionPolicy{"delete", "retain"}, "retain"},
+	}
+
+	for _, tt := range tests {
+		specs := []crd.ObjectStoreBucketSpec{}
+		for _, policy := range tt.policies {
+			specs = append(specs, crd.ObjectStoreBucketSpec{DeletionPolicy: policy})
+		}
+		if got := getDeletionPolicy(specs); got != tt.want {
+			t.Errorf("Wrong deletion policy %s for %v; expected %s", got, tt.policies, tt.want)
+		}
+	}
+}
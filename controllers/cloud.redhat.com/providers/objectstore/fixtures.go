@@ -0,0 +1,220 @@
+package objectstore
+
+import (
+	"fmt"
+	"strings"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1/common"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// MinioFixturesJob is the resource ident for the jobs uploading fixtures to an app's buckets.
+var MinioFixturesJob = providers.NewMultiResourceIdent(ProvName, "minio_fixtures_job", &batch.Job{})
+
+// minioClientImage is the image used to upload fixtures to Minio.
+const minioClientImage = "quay.io/cloudservices/mc:RELEASE.2020-11-25T23-04-07Z"
+
+const fixturesVolume = "fixtures"
+
+const fixturesMountPath = "/fixtures"
+
+// uploadFixturesScript uploads each entry at the top of the fixtures volume, skipping the hidden
+// entries kubernetes creates when mounting a ConfigMap.
+const uploadFixturesScript = `for f in /fixtures/*; do mc cp --recursive "$f" "fixtures/$BUCKET/" || exit 1; done`
+
+// extractFixturesScript copies a directory, or extracts a tarball, from the fixtures image into
+// the fixtures volume.
+const extractFixturesScript = `if [ -d "$FIXTURES_PATH" ]; then cp -R "$FIXTURES_PATH"/. /fixtures/; ` +
+	`else tar -xf "$FIXTURES_PATH" -C /fixtures; fi`
+
+func validateFixtures(bucket string, fixtures *crd.BucketFixtures) error {
+	if (fixtures.ConfigMap == "") == (fixtures.Image == "") {
+		return errors.New(fmt.Sprintf("bucket %q -- fixtures must set exactly one of configMap or image", bucket))
+	}
+	if fixtures.Image != "" && fixtures.Path == "" {
+		return errors.New(fmt.Sprintf("bucket %q -- fixtures from an image must set a path", bucket))
+	}
+	return nil
+}
+
+func getFixturesJobName(app *crd.ClowdApp, spec crd.ObjectStoreBucketSpec) string {
+	return strings.ReplaceAll(fmt.Sprintf("%s-%s-fixtures", app.Name, spec.Name), ".", "-")
+}
+
+// loadFixtures creates a job uploading the fixtures requested for a bucket when the bucket is
+// created. Until the job exists the bucket is tagged as having fixtures pending, so that the job is
+// still created if the reconcile which created the bucket fails. The job is kept afterwards,
+// without being changed, so that its completion can be reported; it is not created for buckets
+// which existed without fixtures pending. Whether the fixtures are still pending is returned.
+func (m *minioProvider) loadFixtures(
+	app *crd.ClowdApp, bucket string, spec crd.ObjectStoreBucketSpec, created bool,
+) (*batch.Job, bool, error) {
+	if spec.Fixtures == nil {
+		return nil, false, nil
+	}
+
+	if err := validateFixtures(spec.Name, spec.Fixtures); err != nil {
+		return nil, false, err
+	}
+
+	nn := types.NamespacedName{
+		Name:      getFixturesJobName(app, spec),
+		Namespace: app.Namespace,
+	}
+
+	// This is a REAL call here, the job must only be added to the cache if it is to be created or
+	// already exists
+	exists := true
+	if err := m.Client.Get(m.Ctx, nn, &batch.Job{}); err != nil {
+		if !k8serr.IsNotFound(err) {
+			return nil, false, err
+		}
+		exists = false
+	}
+
+	if !exists && !created {
+		bucketTags, err := m.BucketHandler.GetTags(m.Ctx, bucket)
+		if err != nil {
+			return nil, false, newBucketError(bucketCheckErrorMsg, bucket, err)
+		}
+		if bucketTags[bucketFixturesPendingTag] != "true" {
+			return nil, false, nil
+		}
+	}
+
+	j := &batch.Job{}
+	if err := m.Cache.Create(MinioFixturesJob, nn, j); err != nil {
+		return nil, false, err
+	}
+
+	if !exists {
+		makeFixturesJob(j, nn, app, m.Config.Hostname, m.Config.Port, bucket, spec.Fixtures)
+	}
+
+	if err := m.Cache.Update(MinioFixturesJob, j); err != nil {
+		return nil, false, err
+	}
+
+	return j, !exists, nil
+}
+
+func makeFixturesJob(
+	j *batch.Job, nn types.NamespacedName, app *crd.ClowdApp,
+	hostname string, port int, bucket string, fixtures *crd.BucketFixtures,
+) {
+	labeler := utils.GetCustomLabeler(nil, nn, app)
+	labeler(j)
+
+	secretKeyRef := func(key string) *core.EnvVarSource {
+		return &core.EnvVarSource{
+			SecretKeyRef: &core.SecretKeySelector{
+				LocalObjectReference: core.LocalObjectReference{Name: getAppSecretName(app)},
+				Key:                  key,
+			},
+		}
+	}
+
+	uploader := core.Container{
+		Name:    "upload",
+		Image:   minioClientImage,
+		Command: []string{"/bin/sh", "-c", uploadFixturesScript},
+		Env: []core.EnvVar{
+			{Name: "HOME", Value: "/tmp"},
+			{Name: "BUCKET", Value: bucket},
+			{Name: "ACCESS_KEY", ValueFrom: secretKeyRef("accessKey")},
+			{Name: "SECRET_KEY", ValueFrom: secretKeyRef("secretKey")},
+			{
+				Name:  "MC_HOST_fixtures",
+				Value: fmt.Sprintf("http://$(ACCESS_KEY):$(SECRET_KEY)@%s:%d", hostname, port),
+			},
+		},
+		VolumeMounts: []core.VolumeMount{{
+			Name:      fixturesVolume,
+			MountPath: fixturesMountPath,
+			ReadOnly:  true,
+		}},
+	}
+
+	volume := core.Volume{Name: fixturesVolume}
+
+	if fixtures.ConfigMap != "" {
+		volume.VolumeSource = core.VolumeSource{
+			ConfigMap: &core.ConfigMapVolumeSource{
+				LocalObjectReference: core.LocalObjectReference{Name: fixtures.ConfigMap},
+			},
+		}
+	} else {
+		volume.VolumeSource = core.VolumeSource{EmptyDir: &core.EmptyDirVolumeSource{}}
+		j.Spec.Template.Spec.InitContainers = []core.Container{{
+			Name:    "extract",
+			Image:   fixtures.Image,
+			Command: []string{"/bin/sh", "-c", extractFixturesScript},
+			Env: []core.EnvVar{
+				{Name: "FIXTURES_PATH", Value: fixtures.Path},
+			},
+			VolumeMounts: []core.VolumeMount{{
+				Name:      fixturesVolume,
+				MountPath: fixturesMountPath,
+			}},
+		}}
+	}
+
+	j.Spec.BackoffLimit = common.Int32Ptr(3)
+	j.Spec.Template.ObjectMeta.Labels = map[string]string{"job": nn.Name}
+	j.Spec.Template.Spec.RestartPolicy = core.RestartPolicyNever
+	j.Spec.Template.Spec.Containers = []core.Container{uploader}
+	j.Spec.Template.Spec.Volumes = []core.Volume{volume}
+}
+
+func isJobFinished(j *batch.Job, conditionType batch.JobConditionType) bool {
+	for _, condition := range j.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == core.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// setFixturesCondition reports whether the fixtures requested for the app's buckets have been
+// uploaded as a condition on the app.
+func setFixturesCondition(app *crd.ClowdApp, jobs []*batch.Job) {
+	if len(jobs) == 0 {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.ObjectStoreFixturesLoaded)
+		return
+	}
+
+	condition := crd.ClowdCondition{
+		Type:    crd.ObjectStoreFixturesLoaded,
+		Status:  core.ConditionTrue,
+		Reason:  "FixturesLoaded",
+		Message: "All fixtures have been uploaded",
+	}
+
+	failed, pending := []string{}, []string{}
+	for _, j := range jobs {
+		if isJobFinished(j, batch.JobFailed) {
+			failed = append(failed, j.Name)
+		} else if !isJobFinished(j, batch.JobComplete) {
+			pending = append(pending, j.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		condition.Status = core.ConditionFalse
+		condition.Reason = "FixturesFailed"
+		condition.Message = fmt.Sprintf("Fixture jobs failed: %s", strings.Join(failed, ", "))
+	} else if len(pending) > 0 {
+		condition.Status = core.ConditionFalse
+		condition.Reason = "FixturesLoading"
+		condition.Message = fmt.Sprintf("Fixture jobs running: %s", strings.Join(pending, ", "))
+	}
+
+	crd.UpdateClowdAppCondition(&app.Status, &condition)
+}
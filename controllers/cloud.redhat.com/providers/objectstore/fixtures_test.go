@@ -0,0 +1,131 @@
+package objectstore
+
+import (
+	"context"
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"github.com/stretchr/testify/assert"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func setupFixturesTest(t *testing.T, exists bool, fixtures *crd.BucketFixtures) (*crd.ClowdApp, *minioProvider) {
+	t.Helper()
+	_, app, mp := setupBucketTest(t, []mockBucket{{Name: "reports", Exists: exists}})
+	app.Spec.ObjectStore = nil
+	app.Spec.ObjectStoreBuckets = []crd.ObjectStoreBucketSpec{{Name: "reports", Fixtures: fixtures}}
+	mp.Client = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	mp.Config.Hostname = "minio.env.svc"
+	mp.Config.Port = 9000
+	return app, mp
+}
+
+func TestMinioFixtures(t *testing.T) {
+	assert := assert.New(t)
+
+	fixturesJobName := types.NamespacedName{Name: "testApp-reports-fixtures", Namespace: "testNamespace"}
+
+	t.Run("configMapFixturesOnCreate", func(t *testing.T) {
+		app, mp := setupFixturesTest(t, false, &crd.BucketFixtures{ConfigMap: "reports-fixtures"})
+
+		assert.NoError(mp.Provide(app, &config.AppConfig{}))
+
+		j := &batch.Job{}
+		assert.NoError(mp.Cache.Get(MinioFixturesJob, j, fixturesJobName))
+		assert.Equal("reports-fixtures", j.Spec.Template.Spec.Volumes[0].ConfigMap.Name)
+		assert.Len(j.Spec.Template.Spec.InitContainers, 0)
+		assert.Contains(j.Spec.Template.Spec.Containers[0].Env, core.EnvVar{Name: "BUCKET", Value: "reports"})
+
+		assert.Len(app.Status.Conditions, 1)
+		assert.Equal(crd.ObjectStoreFixturesLoaded, app.Status.Conditions[0].Type)
+		assert.Equal("FixturesLoading", app.Status.Conditions[0].Reason)
+
+		handler := mp.BucketHandler.(*mockBucketHandler)
+		assert.Equal("true", handler.Settings["reports"].Tags[bucketFixturesPendingTag])
+	})
+
+	t.Run("pendingFixturesForExistingBucket", func(t *testing.T) {
+		app, mp := setupFixturesTest(t, true, &crd.BucketFixtures{ConfigMap: "reports-fixtures"})
+		handler := mp.BucketHandler.(*mockBucketHandler)
+		handler.TaggedBuckets = map[string]map[string]string{"reports": {bucketFixturesPendingTag: "true"}}
+
+		assert.NoError(mp.Provide(app, &config.AppConfig{}))
+
+		j := &batch.Job{}
+		assert.NoError(mp.Cache.Get(MinioFixturesJob, j, fixturesJobName))
+		assert.Equal("true", handler.Settings["reports"].Tags[bucketFixturesPendingTag])
+	})
+
+	t.Run("pendingFixturesClearedOnceJobExists", func(t *testing.T) {
+		app, mp := setupFixturesTest(t, true, &crd.BucketFixtures{ConfigMap: "reports-fixtures"})
+		handler := mp.BucketHandler.(*mockBucketHandler)
+		handler.TaggedBuckets = map[string]map[string]string{"reports": {bucketFixturesPendingTag: "true"}}
+		mp.Client = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			getTestAppSecret(),
+			&batch.Job{ObjectMeta: metav1.ObjectMeta{Name: fixturesJobName.Name, Namespace: fixturesJobName.Namespace}},
+		).Build()
+		cache := providers.NewObjectCache(context.TODO(), mp.Client, clientgoscheme.Scheme)
+		mp.Cache = &cache
+
+		assert.NoError(mp.Provide(app, &config.AppConfig{}))
+
+		assert.NotContains(handler.Settings["reports"].Tags, bucketFixturesPendingTag)
+	})
+
+	t.Run("imageFixturesOnCreate", func(t *testing.T) {
+		fixtures := &crd.BucketFixtures{Image: "quay.io/test/fixtures:latest", Path: "/data/reports.tar.gz"}
+		app, mp := setupFixturesTest(t, false, fixtures)
+
+		assert.NoError(mp.Provide(app, &config.AppConfig{}))
+
+		j := &batch.Job{}
+		assert.NoError(mp.Cache.Get(MinioFixturesJob, j, fixturesJobName))
+		assert.NotNil(j.Spec.Template.Spec.Volumes[0].EmptyDir)
+		assert.Equal(fixtures.Image, j.Spec.Template.Spec.InitContainers[0].Image)
+	})
+
+	t.Run("noFixturesForExistingBucket", func(t *testing.T) {
+		app, mp := setupFixturesTest(t, true, &crd.BucketFixtures{ConfigMap: "reports-fixtures"})
+
+		assert.NoError(mp.Provide(app, &config.AppConfig{}))
+
+		j := &batch.Job{}
+		assert.Error(mp.Cache.Get(MinioFixturesJob, j, fixturesJobName))
+		assert.Len(app.Status.Conditions, 0)
+	})
+
+	t.Run("invalidFixtures", func(t *testing.T) {
+		fixtures := &crd.BucketFixtures{ConfigMap: "reports-fixtures", Image: "quay.io/test/fixtures:latest"}
+		app, mp := setupFixturesTest(t, false, fixtures)
+
+		assert.Error(mp.Provide(app, &config.AppConfig{}))
+	})
+}
+
+func TestSetFixturesCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	makeJob := func(name string, conditionType batch.JobConditionType) *batch.Job {
+		j := &batch.Job{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		j.Status.Conditions = []batch.JobCondition{{Type: conditionType, Status: core.ConditionTrue}}
+		return j
+	}
+
+	app := &crd.ClowdApp{}
+	setFixturesCondition(app, []*batch.Job{makeJob("a", batch.JobComplete)})
+	assert.Equal(core.ConditionTrue, app.Status.Conditions[0].Status)
+
+	setFixturesCondition(app, []*batch.Job{makeJob("a", batch.JobComplete), makeJob("b", batch.JobFailed)})
+	assert.Equal(core.ConditionFalse, app.Status.Conditions[0].Status)
+	assert.Equal("FixturesFailed", app.Status.Conditions[0].Reason)
+
+	setFixturesCondition(app, nil)
+	assert.Len(app.Status.Conditions, 0)
+}
@@ -2,8 +2,12 @@ package objectstore
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
@@ -14,6 +18,7 @@ import (
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -36,8 +41,18 @@ var MinioSecret = providers.NewSingleResourceIdent(ProvName, "minio_db_secret",
 // MinioNetworkPolicy is the resource ident for the KafkaNetworkPolicy
 var MinioNetworkPolicy = providers.NewSingleResourceIdent(ProvName, "minio_network_policy", &networking.NetworkPolicy{})
 
+// MinioAppSecret is the resource ident for the secret holding an app's Minio credentials.
+var MinioAppSecret = providers.NewSingleResourceIdent(ProvName, "minio_app_secret", &core.Secret{})
+
 const bucketCheckErrorMsg = "failed to check if bucket exists"
 const bucketCreateErrorMsg = "failed to create bucket"
+const userCreateErrorMsg = "failed to create minio user"
+const userPolicyErrorMsg = "failed to set minio user policy"
+const userRemoveErrorMsg = "failed to remove minio user"
+
+// appPolicyPrefix prefixes the names of the policies of the users created for apps, to tell them
+// apart from users and policies created by hand.
+const appPolicyPrefix = "clowder-"
 
 func newBucketError(msg string, bucketName string, rootCause error) error {
 	newErr := errors.Wrap(fmt.Sprintf("bucket %q -- %s", bucketName, msg), rootCause)
@@ -50,11 +65,26 @@ type bucketHandler interface {
 	Exists(ctx context.Context, bucketName string) (bool, error)
 	Make(ctx context.Context, bucketName string) error
 	CreateClient(hostname string, port int, accessKey *string, secretKey *string) error
+	CreateUser(ctx context.Context, accessKey string, secretKey string) error
+	SetUserPolicy(ctx context.Context, accessKey string, policyName string, policy []byte) error
+	ListUsers(ctx context.Context) (map[string]string, error)
+	RemoveUser(ctx context.Context, accessKey string, policyName string) error
+	SetTags(ctx context.Context, bucketName string, tags map[string]string) error
+	GetTags(ctx context.Context, bucketName string) (map[string]string, error)
+	SetLifecycle(ctx context.Context, bucketName string, expirationDays *int32) error
+	SetVersioning(ctx context.Context, bucketName string, enabled bool) error
+	SetPublicRead(ctx context.Context, bucketName string, public bool) error
+	ListTagged(ctx context.Context) (map[string]map[string]string, error)
+	Remove(ctx context.Context, bucketName string) error
 }
 
 // minioHandler will implement the above interface using minio-go
 type minioHandler struct {
-	Client *minio.Client
+	Client      *minio.Client
+	AdminClient *minioAdminClient
+	Secure      bool
+	Region      string
+	RootCAs     *x509.CertPool
 }
 
 func (h *minioHandler) Exists(ctx context.Context, bucketName string) (bool, error) {
@@ -62,7 +92,7 @@ func (h *minioHandler) Exists(ctx context.Context, bucketName string) (bool, err
 }
 
 func (h *minioHandler) Make(ctx context.Context, bucketName string) error {
-	return h.Client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
+	return h.Client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: h.Region})
 }
 
 func (h *minioHandler) CreateClient(
@@ -70,34 +100,94 @@ func (h *minioHandler) CreateClient(
 ) error {
 	endpoint := fmt.Sprintf("%v:%v", hostname, port)
 
-	cl, err := minio.New(endpoint, &minio.Options{
+	opts := &minio.Options{
 		Creds:  credentials.NewStaticV4(*accessKey, *secretKey, ""),
-		Secure: false,
-	})
+		Secure: h.Secure,
+		Region: h.Region,
+	}
+
+	if h.Secure && h.RootCAs != nil {
+		transport, err := minio.DefaultTransport(h.Secure)
+		if err != nil {
+			return errors.Wrap("Failed to create minio transport", err)
+		}
+		transport.TLSClientConfig.RootCAs = h.RootCAs
+		opts.Transport = transport
+	}
+
+	cl, err := minio.New(endpoint, opts)
 
 	if err != nil {
 		return errors.Wrap("Failed to create minio client", err)
 	}
 
 	h.Client = cl
+	h.AdminClient = newMinioAdminClient(endpoint, h.Secure, opts.Transport, *accessKey, *secretKey)
 
 	return nil
 }
 
+func (h *minioHandler) CreateUser(ctx context.Context, accessKey string, secretKey string) error {
+	return h.AdminClient.AddUser(ctx, accessKey, secretKey)
+}
+
+func (h *minioHandler) SetUserPolicy(
+	ctx context.Context, accessKey string, policyName string, policy []byte,
+) error {
+	if err := h.AdminClient.AddCannedPolicy(ctx, policyName, policy); err != nil {
+		return err
+	}
+	return h.AdminClient.SetUserPolicy(ctx, policyName, accessKey)
+}
+
+func (h *minioHandler) ListUsers(ctx context.Context) (map[string]string, error) {
+	return h.AdminClient.ListUsers(ctx)
+}
+
+func (h *minioHandler) RemoveUser(ctx context.Context, accessKey string, policyName string) error {
+	// the policy is removed last, as MinIO refuses to remove policies still attached to users
+	if err := h.AdminClient.RemoveUser(ctx, accessKey); err != nil {
+		return err
+	}
+	return h.AdminClient.RemoveCannedPolicy(ctx, policyName)
+}
+
 // minio is an object store provider that deploys and configures MinIO
 type minioProvider struct {
 	providers.Provider
-	Config        config.ObjectStoreConfig
-	BucketHandler bucketHandler
+	Config         config.ObjectStoreConfig
+	BucketHandler  bucketHandler
+	BucketRequests map[string][]crd.ObjectStoreBucketSpec
 }
 
-// Provide creates new buckets
+// Provide creates new buckets, and a Minio user for the app which may only access them and the
+// buckets shared with it by other apps
 func (m *minioProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
-	if len(app.Spec.ObjectStore) == 0 {
+	requestedBuckets := app.GetObjectStoreBuckets()
+	if len(requestedBuckets) == 0 && len(app.Spec.SharedBuckets) == 0 {
+		setFixturesCondition(app, nil)
 		return nil
 	}
 
-	for _, bucket := range app.Spec.ObjectStore {
+	sharedBuckets, err := getSharedBuckets(&m.Provider, app)
+	if err != nil {
+		return err
+	}
+
+	accessKey, secretKey, err := m.getAppCredentials(app)
+	if err != nil {
+		return err
+	}
+
+	bucketNames := []string{}
+	fixturesJobs := []*batch.Job{}
+
+	for _, requestedBucket := range requestedBuckets {
+		bucket, err := getBucketName(m.Env, requestedBucket.Name, app.Namespace)
+		if err != nil {
+			return err
+		}
+
 		found, err := m.BucketHandler.Exists(m.Ctx, bucket)
 
 		if err != nil {
@@ -112,24 +202,244 @@ func (m *minioProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
 			}
 		}
 
+		j, fixturesPending, err := m.loadFixtures(app, bucket, requestedBucket, !found)
+		if err != nil {
+			return err
+		}
+
+		if err := m.configureBucket(bucket, requestedBucket, fixturesPending); err != nil {
+			return err
+		}
+		if j != nil {
+			fixturesJobs = append(fixturesJobs, j)
+		}
+
+		bucketNames = append(bucketNames, bucket)
 		m.Config.Buckets = append(m.Config.Buckets, config.ObjectStoreBucket{
 			Name:          bucket,
-			RequestedName: bucket,
-			AccessKey:     m.Config.AccessKey,
-			SecretKey:     m.Config.SecretKey,
+			RequestedName: requestedBucket.Name,
+			AccessKey:     accessKey,
+			SecretKey:     secretKey,
+		})
+	}
+
+	readOnlyBucketNames := []string{}
+
+	for _, shared := range sharedBuckets {
+		found, err := m.BucketHandler.Exists(m.Ctx, shared.Name)
+
+		if err != nil {
+			return newBucketError(bucketCheckErrorMsg, shared.Name, err)
+		}
+
+		if !found {
+			return newSharedBucketMissingError(shared)
+		}
+
+		if shared.ReadOnly {
+			readOnlyBucketNames = append(readOnlyBucketNames, shared.Name)
+		} else {
+			bucketNames = append(bucketNames, shared.Name)
+		}
+
+		m.Config.Buckets = append(m.Config.Buckets, config.ObjectStoreBucket{
+			Name:          shared.Name,
+			RequestedName: shared.Bucket,
+			AccessKey:     accessKey,
+			SecretKey:     secretKey,
 		})
 	}
+
+	if err := m.createAppUser(app, bucketNames, readOnlyBucketNames, *accessKey, *secretKey); err != nil {
+		return err
+	}
+
+	setFixturesCondition(app, fixturesJobs)
+
 	c.ObjectStore = &config.ObjectStoreConfig{
 		Hostname:  m.Config.Hostname,
 		Port:      m.Config.Port,
-		AccessKey: m.Config.AccessKey,
-		SecretKey: m.Config.SecretKey,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
 		Buckets:   m.Config.Buckets,
 		Tls:       false,
 	}
 	return nil
 }
 
+func getAppSecretName(app *crd.ClowdApp) string {
+	return fmt.Sprintf("%s-minio", app.Name)
+}
+
+// getAppCredentials returns the app's Minio credentials, generating them on first use. They are
+// kept in a secret owned by the app so that they survive restarts of the operator.
+func (m *minioProvider) getAppCredentials(app *crd.ClowdApp) (*string, *string, error) {
+	nn := types.NamespacedName{
+		Name:      getAppSecretName(app),
+		Namespace: app.Namespace,
+	}
+
+	dataInit := func() map[string]string {
+		return map[string]string{
+			"accessKey": utils.RandString(12),
+			"secretKey": utils.RandString(12),
+		}
+	}
+
+	secMap, err := providers.MakeOrGetSecret(m.Ctx, app, m.Cache, MinioAppSecret, nn, dataInit)
+	if err != nil {
+		raisedErr := errors.Wrap("Couldn't set/get app minio secret", err)
+		raisedErr.Requeue = true
+		return nil, nil, raisedErr
+	}
+
+	return providers.StrPtr((*secMap)["accessKey"]), providers.StrPtr((*secMap)["secretKey"]), nil
+}
+
+// createAppUser creates, or updates, the app's Minio user and gives it a policy allowing access to
+// the app's buckets only, and read access to the read-only buckets shared with it.
+func (m *minioProvider) createAppUser(
+	app *crd.ClowdApp, buckets []string, readOnlyBuckets []string, accessKey string, secretKey string,
+) error {
+	if err := m.BucketHandler.CreateUser(m.Ctx, accessKey, secretKey); err != nil {
+		return newUserError(userCreateErrorMsg, app, err)
+	}
+
+	policy, err := makeBucketPolicy(buckets, readOnlyBuckets)
+	if err != nil {
+		return err
+	}
+
+	if err := m.BucketHandler.SetUserPolicy(m.Ctx, accessKey, getAppPolicyName(app), policy); err != nil {
+		return newUserError(userPolicyErrorMsg, app, err)
+	}
+
+	return nil
+}
+
+func getAppPolicyName(app *crd.ClowdApp) string {
+	return fmt.Sprintf("%s%s-%s", appPolicyPrefix, app.Namespace, app.Name)
+}
+
+// EnvProvide removes the Minio users, and their policies, of apps which have been deleted or no
+// longer use any buckets, and the buckets no app has requested for the grace period. The time
+// until the next orphaned bucket is due to be deleted is returned, or zero if none are.
+func (m *minioProvider) EnvProvide() (time.Duration, error) {
+	// users and buckets can only be cleaned up once minio is running
+	dd := &apps.Deployment{}
+	if err := m.Cache.Get(MinioDeployment, dd); err != nil {
+		return 0, err
+	}
+
+	if dd.Status.ReadyReplicas == 0 {
+		return 0, nil
+	}
+
+	// the users are listed before the apps, so that the users of apps created meanwhile are kept
+	users, err := m.BucketHandler.ListUsers(m.Ctx)
+	if err != nil {
+		return 0, errors.Wrap("User cleanup failed: Error listing users", err)
+	}
+
+	appList, err := m.Env.GetAppsInEnv(m.Ctx, m.Client)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := gcUsers(m.Ctx, m.BucketHandler, users, appList); err != nil {
+		return 0, err
+	}
+
+	requests, err := getBucketRequests(m.Env, appList)
+	if err != nil {
+		return 0, err
+	}
+
+	return gcBuckets(m.Ctx, m.Env, m.BucketHandler, requests, time.Now())
+}
+
+// gcUsers removes the users created for apps which no longer need them, and their policies.
+func gcUsers(
+	ctx context.Context, handler bucketHandler, users map[string]string, appList *crd.ClowdAppList,
+) error {
+	wanted := map[string]bool{}
+	for i := range appList.Items {
+		app := &appList.Items[i]
+		if len(app.GetObjectStoreBuckets()) > 0 || len(app.Spec.SharedBuckets) > 0 {
+			wanted[getAppPolicyName(app)] = true
+		}
+	}
+
+	for accessKey, policyName := range users {
+		if !strings.HasPrefix(policyName, appPolicyPrefix) || wanted[policyName] {
+			continue
+		}
+
+		if err := handler.RemoveUser(ctx, accessKey, policyName); err != nil {
+			newErr := errors.Wrap(fmt.Sprintf("policy %q -- %s", policyName, userRemoveErrorMsg), err)
+			newErr.Requeue = true
+			return newErr
+		}
+	}
+
+	return nil
+}
+
+func newUserError(msg string, app *crd.ClowdApp, rootCause error) error {
+	newErr := errors.Wrap(fmt.Sprintf("app %q -- %s", app.Name, msg), rootCause)
+	newErr.Requeue = true
+	return newErr
+}
+
+type bucketPolicyStatement struct {
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+type bucketPolicy struct {
+	Version   string                  `json:"Version"`
+	Statement []bucketPolicyStatement `json:"Statement"`
+}
+
+// makeBucketPolicy returns an IAM policy allowing full access to the given buckets and their
+// objects, and read access to the read-only buckets and their objects.
+func makeBucketPolicy(buckets []string, readOnlyBuckets []string) ([]byte, error) {
+	statements := []bucketPolicyStatement{}
+
+	if len(buckets) > 0 {
+		statements = append(statements, bucketPolicyStatement{
+			Effect:   "Allow",
+			Action:   []string{"s3:*"},
+			Resource: getBucketResources(buckets),
+		})
+	}
+
+	if len(readOnlyBuckets) > 0 {
+		statements = append(statements, bucketPolicyStatement{
+			Effect:   "Allow",
+			Action:   []string{"s3:GetBucketLocation", "s3:ListBucket", "s3:GetObject"},
+			Resource: getBucketResources(readOnlyBuckets),
+		})
+	}
+
+	return json.Marshal(bucketPolicy{
+		Version:   "2012-10-17",
+		Statement: statements,
+	})
+}
+
+func getBucketResources(buckets []string) []string {
+	resources := []string{}
+	for _, bucket := range buckets {
+		resources = append(resources,
+			fmt.Sprintf("arn:aws:s3:::%s", bucket),
+			fmt.Sprintf("arn:aws:s3:::%s/*", bucket),
+		)
+	}
+	return resources
+}
+
 func createMinioProvider(
 	p *providers.Provider, secMap map[string]string, handler bucketHandler,
 ) (*minioProvider, error) {
@@ -203,6 +513,16 @@ func NewMinIO(p *providers.Provider) (providers.ClowderProvider, error) {
 		return nil, raisedErr
 	}
 
+	appList, err := p.Env.GetAppsInEnv(p.Ctx, p.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	mp.BucketRequests, err = getBucketRequests(p.Env, appList)
+	if err != nil {
+		return nil, err
+	}
+
 	return mp, createNetworkPolicy(p)
 }
 
@@ -5,14 +5,22 @@ import (
 	errlib "errors"
 	"strconv"
 	"testing"
+	"time"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
 	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
+const testAppAccessKey = "appAccessKey"
+const testAppSecretKey = "appSecretKey"
+
 // TODO: replace with assert.ErrorIs whenever testify is next released...
 func assertErrorIs(t *testing.T, got error, want error) {
 	t.Helper()
@@ -37,6 +45,23 @@ type mockBucketHandler struct {
 	ExistsCalls           []string
 	MakeCalls             []string
 	MockBuckets           []mockBucket
+	CreateUserCalls       []string
+	CreateUserError       error
+	Policies              map[string][]byte
+	SetUserPolicyError    error
+	Users                 map[string]string
+	RemoveUserCalls       []string
+	Settings              map[string]mockBucketSettings
+	ConfigureError        error
+	TaggedBuckets         map[string]map[string]string
+	RemoveCalls           []string
+}
+
+type mockBucketSettings struct {
+	Tags           map[string]string
+	ExpirationDays *int32
+	Versioning     bool
+	PublicRead     bool
 }
 
 func (c *mockBucketHandler) Exists(ctx context.Context, bucketName string) (bool, error) {
@@ -84,9 +109,84 @@ func (c *mockBucketHandler) CreateClient(
 	return nil
 }
 
+func (c *mockBucketHandler) CreateUser(ctx context.Context, accessKey string, secretKey string) error {
+	// track the calls to this mock func
+	c.CreateUserCalls = append(c.CreateUserCalls, accessKey)
+	return c.CreateUserError
+}
+
+func (c *mockBucketHandler) SetUserPolicy(
+	ctx context.Context, accessKey string, policyName string, policy []byte,
+) error {
+	if c.SetUserPolicyError != nil {
+		return c.SetUserPolicyError
+	}
+	if c.Policies == nil {
+		c.Policies = map[string][]byte{}
+	}
+	c.Policies[accessKey] = policy
+	return nil
+}
+
+func (c *mockBucketHandler) ListUsers(ctx context.Context) (map[string]string, error) {
+	return c.Users, nil
+}
+
+func (c *mockBucketHandler) RemoveUser(ctx context.Context, accessKey string, policyName string) error {
+	// track the calls to this mock func
+	c.RemoveUserCalls = append(c.RemoveUserCalls, accessKey)
+	return nil
+}
+
+func (c *mockBucketHandler) updateSettings(bucketName string, update func(*mockBucketSettings)) error {
+	if c.ConfigureError != nil {
+		return c.ConfigureError
+	}
+	if c.Settings == nil {
+		c.Settings = map[string]mockBucketSettings{}
+	}
+	settings := c.Settings[bucketName]
+	update(&settings)
+	c.Settings[bucketName] = settings
+	return nil
+}
+
+func (c *mockBucketHandler) SetTags(ctx context.Context, bucketName string, tags map[string]string) error {
+	return c.updateSettings(bucketName, func(s *mockBucketSettings) { s.Tags = tags })
+}
+
+func (c *mockBucketHandler) GetTags(ctx context.Context, bucketName string) (map[string]string, error) {
+	if settings, ok := c.Settings[bucketName]; ok {
+		return settings.Tags, nil
+	}
+	return c.TaggedBuckets[bucketName], nil
+}
+
+func (c *mockBucketHandler) SetLifecycle(ctx context.Context, bucketName string, expirationDays *int32) error {
+	return c.updateSettings(bucketName, func(s *mockBucketSettings) { s.ExpirationDays = expirationDays })
+}
+
+func (c *mockBucketHandler) SetVersioning(ctx context.Context, bucketName string, enabled bool) error {
+	return c.updateSettings(bucketName, func(s *mockBucketSettings) { s.Versioning = enabled })
+}
+
+func (c *mockBucketHandler) SetPublicRead(ctx context.Context, bucketName string, public bool) error {
+	return c.updateSettings(bucketName, func(s *mockBucketSettings) { s.PublicRead = public })
+}
+
+func (c *mockBucketHandler) ListTagged(ctx context.Context) (map[string]map[string]string, error) {
+	return c.TaggedBuckets, nil
+}
+
+func (c *mockBucketHandler) Remove(ctx context.Context, bucketName string) error {
+	// track the calls to this mock func
+	c.RemoveCalls = append(c.RemoveCalls, bucketName)
+	return nil
+}
+
 func getTestProvider(t *testing.T) providers.Provider {
 	t.Helper()
-	return providers.Provider{Ctx: context.TODO()}
+	return providers.Provider{Ctx: context.TODO(), Env: &crd.ClowdEnvironment{}}
 }
 
 func getTestMinioProvider(t *testing.T) *minioProvider {
@@ -97,6 +197,39 @@ func getTestMinioProvider(t *testing.T) *minioProvider {
 	return testMinioProvider
 }
 
+// getTestCache returns an object cache backed by a fake client holding the given objects
+func getTestCache(t *testing.T, objs ...*core.Secret) *providers.ObjectCache {
+	t.Helper()
+	builder := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme)
+	for _, o := range objs {
+		builder = builder.WithObjects(o)
+	}
+	cache := providers.NewObjectCache(context.TODO(), builder.Build(), clientgoscheme.Scheme)
+	return &cache
+}
+
+func getTestAppSecret() *core.Secret {
+	return &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testApp-minio",
+			Namespace: "testNamespace",
+		},
+		Data: map[string][]byte{
+			"accessKey": []byte(testAppAccessKey),
+			"secretKey": []byte(testAppSecretKey),
+		},
+	}
+}
+
+func wantBucket(name string) config.ObjectStoreBucket {
+	return config.ObjectStoreBucket{
+		Name:          name,
+		RequestedName: name,
+		AccessKey:     providers.StrPtr(testAppAccessKey),
+		SecretKey:     providers.StrPtr(testAppSecretKey),
+	}
+}
+
 func setupBucketTest(t *testing.T, mockBuckets []mockBucket) (
 	*mockBucketHandler, *crd.ClowdApp, *minioProvider,
 ) {
@@ -106,11 +239,16 @@ func setupBucketTest(t *testing.T, mockBuckets []mockBucket) (
 		bucketNames = append(bucketNames, mb.Name)
 	}
 	testApp := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testApp",
+			Namespace: "testNamespace",
+		},
 		Spec: crd.ClowdAppSpec{
 			ObjectStore: bucketNames,
 		},
 	}
 	testMinioProvider := getTestMinioProvider(t)
+	testMinioProvider.Cache = getTestCache(t, getTestAppSecret())
 	testBucketHandler := &mockBucketHandler{MockBuckets: mockBuckets}
 	testMinioProvider.BucketHandler = testBucketHandler
 	return testBucketHandler, testApp, testMinioProvider
@@ -180,7 +318,7 @@ func TestMinio(t *testing.T) {
 		assert.Len(handler.MakeCalls, 0)
 		assert.Contains(handler.ExistsCalls, bucketName)
 
-		wantBucketConfig := config.ObjectStoreBucket{Name: bucketName, RequestedName: bucketName}
+		wantBucketConfig := wantBucket(bucketName)
 		assert.Contains(mp.Config.Buckets, wantBucketConfig)
 		assert.Len(mp.Config.Buckets, 1)
 	})
@@ -201,7 +339,7 @@ func TestMinio(t *testing.T) {
 		assert.Contains(handler.ExistsCalls, bucketName)
 		assert.Contains(handler.MakeCalls, bucketName)
 
-		wantBucketConfig := config.ObjectStoreBucket{Name: bucketName, RequestedName: bucketName}
+		wantBucketConfig := wantBucket(bucketName)
 		assert.Contains(mp.Config.Buckets, wantBucketConfig)
 		assert.Len(mp.Config.Buckets, 1)
 	})
@@ -223,7 +361,7 @@ func TestMinio(t *testing.T) {
 		assert.Len(handler.MakeCalls, 3)
 		assert.Len(mp.Config.Buckets, 3)
 		for _, b := range []string{b1, b2, b3} {
-			wantBucketConfig := config.ObjectStoreBucket{Name: b, RequestedName: b}
+			wantBucketConfig := wantBucket(b)
 			assert.Contains(mp.Config.Buckets, wantBucketConfig)
 			assert.Contains(handler.ExistsCalls, b)
 			assert.Contains(handler.MakeCalls, b)
@@ -248,7 +386,7 @@ func TestMinio(t *testing.T) {
 		assert.Len(mp.Config.Buckets, 3)
 		for _, b := range []string{b1, b2, b3} {
 			assert.Contains(handler.ExistsCalls, b)
-			wantBucketConfig := config.ObjectStoreBucket{Name: b, RequestedName: b}
+			wantBucketConfig := wantBucket(b)
 			assert.Contains(mp.Config.Buckets, wantBucketConfig)
 		}
 		assert.Contains(handler.MakeCalls, b3)
@@ -274,7 +412,7 @@ func TestMinio(t *testing.T) {
 		assert.Len(handler.ExistsCalls, 2)
 		assert.Len(handler.MakeCalls, 1)
 		assert.Len(mp.Config.Buckets, 1)
-		wantBucketConfig := config.ObjectStoreBucket{Name: b1, RequestedName: b1}
+		wantBucketConfig := wantBucket(b1)
 		assert.Contains(mp.Config.Buckets, wantBucketConfig)
 	})
 
@@ -298,10 +436,192 @@ func TestMinio(t *testing.T) {
 		assert.Len(handler.ExistsCalls, 2)
 		assert.Len(handler.MakeCalls, 2)
 		assert.Len(mp.Config.Buckets, 1)
-		wantBucketConfig := config.ObjectStoreBucket{Name: b1, RequestedName: b1}
+		wantBucketConfig := wantBucket(b1)
 		assert.Contains(mp.Config.Buckets, wantBucketConfig)
 	})
 
+	t.Run("createAppUser", func(t *testing.T) {
+		b1, b2 := "testBucket1", "testBucket2"
+
+		mockBuckets := []mockBucket{
+			{Name: b1, Exists: false},
+			{Name: b2, Exists: true},
+		}
+		c := config.AppConfig{}
+
+		handler, app, mp := setupBucketTest(t, mockBuckets)
+		gotErr := mp.Provide(app, &c)
+		assert.NoError(gotErr)
+		assert.Equal([]string{testAppAccessKey}, handler.CreateUserCalls)
+		assert.Equal(testAppAccessKey, *c.ObjectStore.AccessKey)
+		assert.Equal(testAppSecretKey, *c.ObjectStore.SecretKey)
+
+		wantPolicy, _ := makeBucketPolicy([]string{b1, b2}, nil)
+		assert.Equal(string(wantPolicy), string(handler.Policies[testAppAccessKey]))
+		assert.Contains(string(wantPolicy), "arn:aws:s3:::testBucket2/*")
+	})
+
+	t.Run("createAppUserGeneratesCredentials", func(t *testing.T) {
+		bucketName := "testBucket"
+		handler, app, mp := setupBucketTest(t, []mockBucket{{Name: bucketName}})
+		mp.Cache = getTestCache(t)
+		c := config.AppConfig{}
+
+		gotErr := mp.Provide(app, &c)
+		assert.NoError(gotErr)
+		assert.Len(handler.CreateUserCalls, 1)
+		assert.Len(*c.ObjectStore.AccessKey, 12)
+		assert.NotEqual(testAppAccessKey, *c.ObjectStore.AccessKey)
+		assert.Equal(*c.ObjectStore.AccessKey, *mp.Config.Buckets[0].AccessKey)
+	})
+
+	t.Run("createAppUserHitsError", func(t *testing.T) {
+		bucketName := "testBucket"
+		handler, app, mp := setupBucketTest(t, []mockBucket{{Name: bucketName}})
+		handler.CreateUserError = fakeError
+		c := config.AppConfig{}
+
+		gotErr := mp.Provide(app, &c)
+		wantErr := newUserError(userCreateErrorMsg, app, fakeError)
+		assert.Error(gotErr)
+		assertErrorIs(t, gotErr, wantErr)
+		assert.Nil(c.ObjectStore)
+	})
+
+	t.Run("setUserPolicyHitsError", func(t *testing.T) {
+		bucketName := "testBucket"
+		handler, app, mp := setupBucketTest(t, []mockBucket{{Name: bucketName}})
+		handler.SetUserPolicyError = fakeError
+		c := config.AppConfig{}
+
+		gotErr := mp.Provide(app, &c)
+		wantErr := newUserError(userPolicyErrorMsg, app, fakeError)
+		assert.Error(gotErr)
+		assertErrorIs(t, gotErr, wantErr)
+		assert.Nil(c.ObjectStore)
+	})
+
+	t.Run("createBucketsTemplated", func(t *testing.T) {
+		handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "testnamespace-uploads"}})
+		app.Spec.ObjectStore = []string{"uploads"}
+		mp.Env.Spec.Providers.ObjectStore.BucketNamingTemplate = "{{.Namespace}}-{{.Bucket}}"
+		app.Namespace = "testnamespace"
+		mp.Cache = getTestCache(t)
+		c := config.AppConfig{}
+
+		gotErr := mp.Provide(app, &c)
+		assert.NoError(gotErr)
+		assert.Equal([]string{"testnamespace-uploads"}, handler.MakeCalls)
+		assert.Equal("testnamespace-uploads", mp.Config.Buckets[0].Name)
+		assert.Equal("uploads", mp.Config.Buckets[0].RequestedName)
+		assert.Contains(string(handler.Policies[*c.ObjectStore.AccessKey]), "arn:aws:s3:::testnamespace-uploads")
+	})
+
+	t.Run("configureBuckets", func(t *testing.T) {
+		expiration := int32(30)
+		handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "plain"}})
+		app.Spec.ObjectStoreBuckets = []crd.ObjectStoreBucketSpec{{
+			Name:           "settings",
+			ExpirationDays: &expiration,
+			Versioning:     true,
+			PublicRead:     true,
+			DeletionPolicy: "delete",
+		}}
+		mp.Env.Name = "env"
+		c := config.AppConfig{}
+
+		gotErr := mp.Provide(app, &c)
+		assert.NoError(gotErr)
+		assert.Equal([]string{"settings", "plain"}, handler.MakeCalls)
+		assert.Equal(mockBucketSettings{
+			Tags:           map[string]string{bucketEnvTag: "env", bucketDeletionPolicyTag: "delete"},
+			ExpirationDays: &expiration,
+			Versioning:     true,
+			PublicRead:     true,
+		}, handler.Settings["settings"])
+		assert.Equal(mockBucketSettings{
+			Tags: map[string]string{bucketEnvTag: "env", bucketDeletionPolicyTag: "retain"},
+		}, handler.Settings["plain"])
+	})
+
+	t.Run("configureBucketsSharedRetained", func(t *testing.T) {
+		handler, app, mp := setupBucketTest(t, []mockBucket{})
+		app.Spec.ObjectStoreBuckets = []crd.ObjectStoreBucketSpec{{Name: "shared", DeletionPolicy: "delete"}}
+		mp.BucketRequests = map[string][]crd.ObjectStoreBucketSpec{
+			"shared": {{Name: "shared", DeletionPolicy: "delete"}, {Name: "shared"}},
+		}
+		c := config.AppConfig{}
+
+		gotErr := mp.Provide(app, &c)
+		assert.NoError(gotErr)
+		assert.Equal("retain", handler.Settings["shared"].Tags[bucketDeletionPolicyTag])
+	})
+
+	t.Run("configureBucketsHitsError", func(t *testing.T) {
+		bucketName := "testBucket"
+		handler, app, mp := setupBucketTest(t, []mockBucket{{Name: bucketName}})
+		handler.ConfigureError = fakeError
+		c := config.AppConfig{}
+
+		gotErr := mp.Provide(app, &c)
+		wantErr := newBucketError(bucketConfigureErrorMsg, bucketName, fakeError)
+		assert.Error(gotErr)
+		assertErrorIs(t, gotErr, wantErr)
+		assert.Len(mp.Config.Buckets, 0)
+	})
+
+	t.Run("gcBuckets", func(t *testing.T) {
+		env := &crd.ClowdEnvironment{}
+		env.Name = "env"
+		now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+		handler := &mockBucketHandler{TaggedBuckets: map[string]map[string]string{
+			"requested":   {bucketEnvTag: "env", bucketDeletionPolicyTag: "delete"},
+			"rerequested": {bucketEnvTag: "env", bucketDeletionPolicyTag: "delete", bucketOrphanedTag: "2022-01-01T11:00:00Z"},
+			"orphaned":    {bucketEnvTag: "env", bucketDeletionPolicyTag: "delete"},
+			"expiring":    {bucketEnvTag: "env", bucketDeletionPolicyTag: "delete", bucketOrphanedTag: "2022-01-01T11:30:00Z"},
+			"expired":     {bucketEnvTag: "env", bucketDeletionPolicyTag: "delete", bucketOrphanedTag: "2022-01-01T11:00:00Z"},
+			"retained":    {bucketEnvTag: "env", bucketDeletionPolicyTag: "retain"},
+			"other-env":   {bucketEnvTag: "other", bucketDeletionPolicyTag: "delete"},
+		}}
+		requests := map[string][]crd.ObjectStoreBucketSpec{
+			"requested":   {{Name: "requested"}},
+			"rerequested": {{Name: "rerequested"}},
+		}
+
+		nextExpiry, gotErr := gcBuckets(context.TODO(), env, handler, requests, now)
+		assert.NoError(gotErr)
+		assert.Equal([]string{"expired"}, handler.RemoveCalls)
+		assert.Equal(30*time.Minute, nextExpiry)
+		assert.Equal("2022-01-01T12:00:00Z", handler.Settings["orphaned"].Tags[bucketOrphanedTag])
+		assert.NotContains(handler.Settings["rerequested"].Tags, bucketOrphanedTag)
+		assert.NotContains(handler.Settings, "requested")
+		assert.NotContains(handler.Settings, "expiring")
+	})
+
+	t.Run("gcUsers", func(t *testing.T) {
+		handler := &mockBucketHandler{}
+		users := map[string]string{
+			"withBuckets":    "clowder-ns-withBuckets",
+			"withShared":     "clowder-ns-withShared",
+			"withoutBuckets": "clowder-ns-withoutBuckets",
+			"deleted":        "clowder-ns-deleted",
+			"byHand":         "readwrite",
+		}
+		appList := &crd.ClowdAppList{Items: []crd.ClowdApp{{
+			ObjectMeta: metav1.ObjectMeta{Name: "withBuckets", Namespace: "ns"},
+			Spec:       crd.ClowdAppSpec{ObjectStore: []string{"bucket"}},
+		}, {
+			ObjectMeta: metav1.ObjectMeta{Name: "withShared", Namespace: "ns"},
+			Spec:       crd.ClowdAppSpec{SharedBuckets: []crd.SharedBucketSpec{{App: "withBuckets", Bucket: "bucket"}}},
+		}, {
+			ObjectMeta: metav1.ObjectMeta{Name: "withoutBuckets", Namespace: "ns"},
+		}}}
+
+		gotErr := gcUsers(context.TODO(), handler, users, appList)
+		assert.NoError(gotErr)
+		assert.ElementsMatch([]string{"withoutBuckets", "deleted"}, handler.RemoveUserCalls)
+	})
+
 	t.Run("minioProviderCreate", func(t *testing.T) {
 		secMap := map[string]string{
 			"accessKey": "123456abcdef",
@@ -0,0 +1,275 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"github.com/minio/minio-go/v7/pkg/signer"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// minioAdminTimeout bounds each admin request, so that an unresponsive MinIO cannot hold up a
+// reconcile indefinitely.
+const minioAdminTimeout = 10 * time.Second
+
+// adminFragmentSize is the plaintext size of each fragment of an encrypted admin payload.
+const adminFragmentSize = 16 * 1024
+
+// minioAdminClient calls the small part of the MinIO admin API needed to manage per-app users
+// and their policies.
+type minioAdminClient struct {
+	endpoint  string
+	scheme    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// newMinioAdminClient returns an admin client using https when secure is set. The transport, which
+// may be nil to use the default one, should be the one given to the bucket client, so that both
+// trust the same CAs.
+func newMinioAdminClient(
+	endpoint string, secure bool, transport http.RoundTripper, accessKey string, secretKey string,
+) *minioAdminClient {
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+
+	return &minioAdminClient{
+		endpoint:  endpoint,
+		scheme:    scheme,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: minioAdminTimeout, Transport: transport},
+	}
+}
+
+// AddUser creates the user, or resets its secret key if it already exists.
+func (a *minioAdminClient) AddUser(ctx context.Context, accessKey string, secretKey string) error {
+	userInfo, err := json.Marshal(map[string]string{
+		"secretKey": secretKey,
+		"status":    "enabled",
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := encryptAdminData(a.secretKey, userInfo)
+	if err != nil {
+		return errors.Wrap("failed to encrypt minio user info", err)
+	}
+
+	_, err = a.do(ctx, http.MethodPut, "add-user", url.Values{"accessKey": {accessKey}}, body)
+	return err
+}
+
+// RemoveUser deletes the user.
+func (a *minioAdminClient) RemoveUser(ctx context.Context, accessKey string) error {
+	_, err := a.do(ctx, http.MethodDelete, "remove-user", url.Values{"accessKey": {accessKey}}, nil)
+	return err
+}
+
+// ListUsers returns the name of the policy attached to each user, keyed by the user's access key.
+func (a *minioAdminClient) ListUsers(ctx context.Context) (map[string]string, error) {
+	resp, err := a.do(ctx, http.MethodGet, "list-users", url.Values{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decryptAdminData(a.secretKey, resp)
+	if err != nil {
+		return nil, errors.Wrap("failed to decrypt minio user list", err)
+	}
+
+	users := map[string]struct {
+		PolicyName string `json:"policyName"`
+	}{}
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, err
+	}
+
+	policies := map[string]string{}
+	for accessKey, user := range users {
+		policies[accessKey] = user.PolicyName
+	}
+
+	return policies, nil
+}
+
+// AddCannedPolicy creates the named policy, replacing any existing policy of the same name.
+func (a *minioAdminClient) AddCannedPolicy(ctx context.Context, policyName string, policy []byte) error {
+	_, err := a.do(ctx, http.MethodPut, "add-canned-policy", url.Values{"name": {policyName}}, policy)
+	return err
+}
+
+// RemoveCannedPolicy deletes the named policy.
+func (a *minioAdminClient) RemoveCannedPolicy(ctx context.Context, policyName string) error {
+	_, err := a.do(ctx, http.MethodDelete, "remove-canned-policy", url.Values{"name": {policyName}}, nil)
+	return err
+}
+
+// SetUserPolicy attaches the named policy to the user.
+func (a *minioAdminClient) SetUserPolicy(ctx context.Context, policyName string, accessKey string) error {
+	_, err := a.do(ctx, http.MethodPut, "set-user-or-group-policy", url.Values{
+		"policyName":  {policyName},
+		"userOrGroup": {accessKey},
+		"isGroup":     {"false"},
+	}, nil)
+	return err
+}
+
+// do sends a signed admin request and returns the body of the response.
+func (a *minioAdminClient) do(
+	ctx context.Context, method string, path string, query url.Values, body []byte,
+) ([]byte, error) {
+	u := url.URL{
+		Scheme:   a.scheme,
+		Host:     a.endpoint,
+		Path:     fmt.Sprintf("/minio/admin/v3/%s", path),
+		RawQuery: query.Encode(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	req.ContentLength = int64(len(body))
+	req = signer.SignV4(*req, a.accessKey, a.secretKey, "", "us-east-1")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(fmt.Sprintf("minio admin %s request failed", path), err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(fmt.Sprintf("minio admin %s request failed", path), err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("minio admin %s request failed: %s %s", path, resp.Status, respBody))
+	}
+
+	return respBody, nil
+}
+
+// encryptAdminData encrypts data in the format the MinIO admin API expects secrets in, which is
+// salt || AEAD id || nonce || ciphertext. The key is derived from the admin secret key with
+// argon2id and the data is sealed with AES-256-GCM as a single, final, sio stream fragment.
+func encryptAdminData(password string, data []byte) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAdminAEAD(password, salt, adminAesGcmID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the last four bytes of the nonce hold the fragment sequence number
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce[:len(nonce)-4]); err != nil {
+		return nil, err
+	}
+
+	// sequence number 0 authenticates the, empty, associated data of the stream, and the
+	// resulting tag, flagged as belonging to the final fragment, is the fragment's associated data
+	associatedData := aead.Seal([]byte{0x80}, nonce, nil, nil)
+
+	binary.LittleEndian.PutUint32(nonce[len(nonce)-4:], 1)
+
+	out := append(salt, adminAesGcmID)
+	out = append(out, nonce[:len(nonce)-4]...)
+
+	return aead.Seal(out, nonce, data, associatedData), nil
+}
+
+// decryptAdminData decrypts a payload encrypted by encryptAdminData, or by MinIO, which may seal
+// it with ChaCha20-Poly1305 instead and split it across several stream fragments.
+func decryptAdminData(password string, data []byte) ([]byte, error) {
+	const headerSize = 32 + 1 + 8
+
+	if len(data) < headerSize {
+		return nil, errors.New("encrypted minio admin data is too short")
+	}
+
+	aead, err := newAdminAEAD(password, data[:32], data[32])
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, data[33:headerSize])
+
+	associatedData := aead.Seal([]byte{0x00}, nonce, nil, nil)
+
+	ciphertext := data[headerSize:]
+	fragmentSize := adminFragmentSize + aead.Overhead()
+	out := []byte{}
+
+	for seq := uint32(1); ; seq++ {
+		fragment := ciphertext
+		final := len(ciphertext) <= fragmentSize
+		if final {
+			associatedData[0] = 0x80
+		} else {
+			fragment = ciphertext[:fragmentSize]
+		}
+
+		binary.LittleEndian.PutUint32(nonce[len(nonce)-4:], seq)
+
+		out, err = aead.Open(out, nonce, fragment, associatedData)
+		if err != nil {
+			return nil, err
+		}
+
+		if final {
+			return out, nil
+		}
+		ciphertext = ciphertext[fragmentSize:]
+	}
+}
+
+const (
+	adminAesGcmID           = 0x00
+	adminChaCha20Poly1305ID = 0x01
+)
+
+// newAdminAEAD derives the key for an admin payload from the admin secret key with argon2id and
+// returns the cipher identified by id.
+func newAdminAEAD(password string, salt []byte, id byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
+
+	switch id {
+	case adminAesGcmID:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case adminChaCha20Poly1305ID:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported minio admin cipher %d", id))
+	}
+}
@@ -0,0 +1,49 @@
+package objectstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminDataEncryption(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []byte(`{"user":{"policyName":"clowder-ns-app","status":"enabled"}}`)
+
+	encrypted, err := encryptAdminData("adminSecret", data)
+	assert.NoError(err)
+
+	decrypted, err := decryptAdminData("adminSecret", encrypted)
+	assert.NoError(err)
+	assert.Equal(data, decrypted)
+
+	_, err = decryptAdminData("wrongSecret", encrypted)
+	assert.Error(err)
+
+	_, err = decryptAdminData("adminSecret", encrypted[:20])
+	assert.Error(err)
+}
+
+func TestAdminClientSecure(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/minio/admin/v3/remove-user", r.URL.Path)
+		assert.Equal("app", r.URL.Query().Get("accessKey"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	endpoint := strings.TrimPrefix(server.URL, "https://")
+
+	client := newMinioAdminClient(endpoint, true, server.Client().Transport, "admin", "adminSecret")
+	assert.NoError(client.RemoveUser(context.TODO(), "app"))
+
+	client = newMinioAdminClient(endpoint, false, server.Client().Transport, "admin", "adminSecret")
+	assert.Error(client.RemoveUser(context.TODO(), "app"))
+}
@@ -0,0 +1,65 @@
+package objectstore
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// bucketNameData is passed to the environment's bucket naming template.
+type bucketNameData struct {
+	Bucket    string
+	Env       string
+	Namespace string
+}
+
+func renderBucketName(env *crd.ClowdEnvironment, bucketName string, namespace string) (string, error) {
+	tmpl, err := template.New("bucketName").Parse(env.Spec.Providers.ObjectStore.BucketNamingTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var name strings.Builder
+	if err := tmpl.Execute(&name, bucketNameData{Bucket: bucketName, Env: env.Name, Namespace: namespace}); err != nil {
+		return "", err
+	}
+
+	if err := s3utils.CheckValidBucketNameStrict(name.String()); err != nil {
+		return "", errors.Wrap("template rendered an invalid bucket name", err)
+	}
+
+	return name.String(), nil
+}
+
+// validateBucketNamingTemplate returns an error if the environment's bucket naming template cannot
+// be rendered.
+func validateBucketNamingTemplate(env *crd.ClowdEnvironment) error {
+	if env.Spec.Providers.ObjectStore.BucketNamingTemplate == "" {
+		return nil
+	}
+
+	if _, err := renderBucketName(env, "bucket", "namespace"); err != nil {
+		return errors.Wrap("invalid objectStore bucketNamingTemplate", err)
+	}
+
+	return nil
+}
+
+// getBucketName returns the actual name of a bucket requested by an app in the given namespace.
+// If the environment has no bucket naming template the requested name is used as is.
+func getBucketName(env *crd.ClowdEnvironment, bucketName string, namespace string) (string, error) {
+	if env.Spec.Providers.ObjectStore.BucketNamingTemplate == "" {
+		return bucketName, nil
+	}
+
+	name, err := renderBucketName(env, bucketName, namespace)
+	if err != nil {
+		return "", errors.Wrap(fmt.Sprintf("could not name bucket %q", bucketName), err)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,59 @@
+package objectstore
+
+import (
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getTemplatedEnv(template string) *crd.ClowdEnvironment {
+	env := &crd.ClowdEnvironment{ObjectMeta: metav1.ObjectMeta{Name: "env"}}
+	env.Spec.Providers.ObjectStore.BucketNamingTemplate = template
+	return env
+}
+
+func TestBucketNamingTemplate(t *testing.T) {
+	env := getTemplatedEnv("{{.Namespace}}-{{.Bucket}}")
+
+	name, err := getBucketName(env, "uploads", "app-ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "app-ns-uploads" {
+		t.Errorf("Wrong bucket name %s; expected app-ns-uploads", name)
+	}
+
+	name, err = getBucketName(getTemplatedEnv(""), "uploads", "app-ns")
+	if err != nil || name != "uploads" {
+		t.Errorf("Wrong untemplated bucket name %s: %v", name, err)
+	}
+
+	for _, tmpl := range []string{"{{.Bucket", "{{.Unknown}}", "{{.Bucket}}_{{.Env}}"} {
+		if err := validateBucketNamingTemplate(getTemplatedEnv(tmpl)); err == nil {
+			t.Errorf("Template %q should be rejected", tmpl)
+		}
+	}
+}
+
+func TestResolveBucketDepsTemplated(t *testing.T) {
+	env := getTemplatedEnv("{{.Namespace}}-{{.Bucket}}")
+
+	c := &config.ObjectStoreConfig{Buckets: []config.ObjectStoreBucket{
+		{Name: "uploads-other"},
+		{Name: "app-ns-uploads"},
+	}}
+
+	if err := resolveBucketDeps([]string{"uploads"}, c, env, "app-ns"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Buckets) != 1 || c.Buckets[0].Name != "app-ns-uploads" || c.Buckets[0].RequestedName != "uploads" {
+		t.Errorf("Wrong buckets %+v", c.Buckets)
+	}
+
+	c = &config.ObjectStoreConfig{Buckets: []config.ObjectStoreBucket{{Name: "uploads-other"}}}
+	if err := resolveBucketDeps([]string{"uploads"}, c, env, "app-ns"); err == nil {
+		t.Error("Bucket without the templated name should not satisfy the request")
+	}
+}
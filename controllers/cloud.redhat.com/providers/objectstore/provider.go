@@ -12,12 +12,18 @@ var ProvName = "objectstore"
 
 // GetObjectStore returns the correct object store provider based on the environment.
 func GetObjectStore(c *providers.Provider) (providers.ClowderProvider, error) {
+	if err := validateBucketNamingTemplate(c.Env); err != nil {
+		return nil, err
+	}
+
 	objectStoreMode := c.Env.Spec.Providers.ObjectStore.Mode
 	switch objectStoreMode {
 	case "minio":
 		return NewMinIO(c)
 	case "app-interface":
 		return &appInterfaceObjectstoreProvider{Provider: *c}, nil
+	case "s3":
+		return NewS3ObjectStore(c)
 	case "none", "":
 		return NewNoneObjectStore(c)
 	default:
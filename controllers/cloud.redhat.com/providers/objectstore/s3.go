@@ -0,0 +1,234 @@
+package objectstore
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// s3Settings holds the connection details read from the env's S3 secret.
+type s3Settings struct {
+	Hostname  string
+	Port      int
+	Region    string
+	Tls       bool
+	Cacert    string
+	AccessKey string
+	SecretKey string
+}
+
+// parseS3Secret reads the connection details for an S3-compatible object store from the data of
+// the secret referenced by the env. The endpoint may be given as host or host:port, tls defaults
+// to true and the port defaults to 443, or 80 when tls is disabled.
+func parseS3Secret(data map[string][]byte) (*s3Settings, error) {
+	for _, key := range []string{"endpoint", "accessKey", "secretKey"} {
+		if len(data[key]) == 0 {
+			return nil, errors.New(fmt.Sprintf("no %s in s3 secret", key))
+		}
+	}
+
+	settings := &s3Settings{
+		Region:    string(data["region"]),
+		Tls:       true,
+		Cacert:    string(data["ca"]),
+		AccessKey: string(data["accessKey"]),
+		SecretKey: string(data["secretKey"]),
+	}
+
+	if tls, ok := data["tls"]; ok {
+		useTLS, err := strconv.ParseBool(string(tls))
+		if err != nil {
+			return nil, errors.Wrap("invalid tls value in s3 secret", err)
+		}
+		settings.Tls = useTLS
+	}
+
+	endpoint := string(data["endpoint"])
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		// no port given, use the default for the scheme
+		settings.Hostname = endpoint
+		settings.Port = 443
+		if !settings.Tls {
+			settings.Port = 80
+		}
+		return settings, nil
+	}
+
+	settings.Hostname = host
+	settings.Port, err = strconv.Atoi(port)
+	if err != nil {
+		return nil, errors.Wrap("invalid port in s3 endpoint", err)
+	}
+
+	return settings, nil
+}
+
+// s3Provider is an object store provider that creates buckets in an existing S3-compatible
+// object store, such as Ceph RGW.
+type s3Provider struct {
+	providers.Provider
+	Config        config.ObjectStoreConfig
+	BucketHandler bucketHandler
+}
+
+// NewS3ObjectStore returns a new s3 object store provider object.
+func NewS3ObjectStore(p *providers.Provider) (providers.ClowderProvider, error) {
+	secretRef := types.NamespacedName{
+		Name:      p.Env.Spec.Providers.ObjectStore.SecretRef.Name,
+		Namespace: p.Env.Spec.Providers.ObjectStore.SecretRef.Namespace,
+	}
+
+	nullName := types.NamespacedName{}
+
+	if secretRef == nullName {
+		return nil, errors.New("no secret ref defined for s3 object store")
+	}
+
+	s := &core.Secret{}
+
+	if err := p.Client.Get(p.Ctx, secretRef, s); err != nil {
+		return nil, err
+	}
+
+	settings, err := parseS3Secret(s.Data)
+	if err != nil {
+		return nil, errors.Wrap("invalid s3 secret", err)
+	}
+
+	handler := &minioHandler{
+		Secure: settings.Tls,
+		Region: settings.Region,
+	}
+
+	if settings.Cacert != "" {
+		handler.RootCAs = x509.NewCertPool()
+		if !handler.RootCAs.AppendCertsFromPEM([]byte(settings.Cacert)) {
+			return nil, errors.New("invalid ca in s3 secret")
+		}
+	}
+
+	return createS3Provider(p, settings, handler)
+}
+
+func createS3Provider(
+	p *providers.Provider, settings *s3Settings, handler bucketHandler,
+) (*s3Provider, error) {
+	sp := &s3Provider{Provider: *p, Config: config.ObjectStoreConfig{}}
+
+	sp.Config.Hostname = settings.Hostname
+	sp.Config.Port = settings.Port
+	sp.Config.AccessKey = providers.StrPtr(settings.AccessKey)
+	sp.Config.SecretKey = providers.StrPtr(settings.SecretKey)
+	sp.Config.Tls = settings.Tls
+
+	if settings.Region != "" {
+		sp.Config.Region = providers.StrPtr(settings.Region)
+	}
+
+	if settings.Cacert != "" {
+		sp.Config.Cacert = providers.StrPtr(settings.Cacert)
+	}
+
+	sp.BucketHandler = handler
+	err := sp.BucketHandler.CreateClient(
+		sp.Config.Hostname,
+		sp.Config.Port,
+		sp.Config.AccessKey,
+		sp.Config.SecretKey,
+	)
+
+	if err != nil {
+		return nil, errors.Wrap("error creating s3 client", err)
+	}
+	return sp, nil
+}
+
+// Provide creates the app's buckets in the object store if they do not exist yet, and adds the
+// buckets shared with it by other apps
+func (s *s3Provider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
+	requestedBuckets := app.GetObjectStoreBuckets()
+	if len(requestedBuckets) == 0 && len(app.Spec.SharedBuckets) == 0 {
+		return nil
+	}
+
+	sharedBuckets, err := getSharedBuckets(&s.Provider, app)
+	if err != nil {
+		return err
+	}
+
+	buckets := []config.ObjectStoreBucket{}
+
+	for _, requestedBucket := range requestedBuckets {
+		bucket, err := getBucketName(s.Env, requestedBucket.Name, app.Namespace)
+		if err != nil {
+			return err
+		}
+
+		found, err := s.BucketHandler.Exists(s.Ctx, bucket)
+
+		if err != nil {
+			return newBucketError(bucketCheckErrorMsg, bucket, err)
+		}
+
+		if !found {
+			err = s.BucketHandler.Make(s.Ctx, bucket)
+
+			if err != nil {
+				return newBucketError(bucketCreateErrorMsg, bucket, err)
+			}
+		}
+
+		buckets = append(buckets, config.ObjectStoreBucket{
+			Name:          bucket,
+			RequestedName: requestedBucket.Name,
+			AccessKey:     s.Config.AccessKey,
+			SecretKey:     s.Config.SecretKey,
+			Region:        s.Config.Region,
+		})
+	}
+
+	for _, shared := range sharedBuckets {
+		if shared.ReadOnly {
+			return newReadOnlyUnsupportedError("s3", shared)
+		}
+
+		found, err := s.BucketHandler.Exists(s.Ctx, shared.Name)
+
+		if err != nil {
+			return newBucketError(bucketCheckErrorMsg, shared.Name, err)
+		}
+
+		if !found {
+			return newSharedBucketMissingError(shared)
+		}
+
+		buckets = append(buckets, config.ObjectStoreBucket{
+			Name:          shared.Name,
+			RequestedName: shared.Bucket,
+			AccessKey:     s.Config.AccessKey,
+			SecretKey:     s.Config.SecretKey,
+			Region:        s.Config.Region,
+		})
+	}
+
+	c.ObjectStore = &config.ObjectStoreConfig{
+		Hostname:  s.Config.Hostname,
+		Port:      s.Config.Port,
+		AccessKey: s.Config.AccessKey,
+		SecretKey: s.Config.SecretKey,
+		Region:    s.Config.Region,
+		Cacert:    s.Config.Cacert,
+		Buckets:   buckets,
+		Tls:       s.Config.Tls,
+	}
+	return nil
+}
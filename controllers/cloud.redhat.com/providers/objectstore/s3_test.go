@@ -0,0 +1,81 @@
+package objectstore
+
+import (
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseS3Secret(t *testing.T) {
+	assert := assert.New(t)
+
+	data := map[string][]byte{
+		"endpoint":  []byte("rgw.example.com:8443"),
+		"region":    []byte("eu-west-1"),
+		"accessKey": []byte("access"),
+		"secretKey": []byte("secret"),
+	}
+
+	settings, err := parseS3Secret(data)
+	assert.NoError(err)
+	assert.Equal("rgw.example.com", settings.Hostname)
+	assert.Equal(8443, settings.Port)
+	assert.Equal("eu-west-1", settings.Region)
+	assert.True(settings.Tls)
+
+	data["endpoint"] = []byte("rgw.example.com")
+	data["tls"] = []byte("false")
+	settings, err = parseS3Secret(data)
+	assert.NoError(err)
+	assert.Equal(80, settings.Port)
+	assert.False(settings.Tls)
+
+	data["tls"] = []byte("maybe")
+	_, err = parseS3Secret(data)
+	assert.Error(err)
+
+	delete(data, "secretKey")
+	_, err = parseS3Secret(data)
+	assert.Error(err)
+}
+
+func TestS3Provide(t *testing.T) {
+	assert := assert.New(t)
+
+	settings := &s3Settings{
+		Hostname:  "rgw.example.com",
+		Port:      443,
+		Region:    "eu-west-1",
+		Tls:       true,
+		AccessKey: "access",
+		SecretKey: "secret",
+	}
+
+	handler := &mockBucketHandler{MockBuckets: []mockBucket{
+		{Name: "exists", Exists: true},
+		{Name: "missing", Exists: false},
+	}}
+
+	p := getTestProvider(t)
+	sp, err := createS3Provider(&p, settings, handler)
+	assert.NoError(err)
+	assert.Equal("rgw.example.com", handler.hostname)
+
+	app := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "testApp", Namespace: "testNamespace"},
+		Spec:       crd.ClowdAppSpec{ObjectStore: []string{"exists", "missing"}},
+	}
+
+	c := config.AppConfig{}
+	assert.NoError(sp.Provide(app, &c))
+
+	assert.Equal([]string{"missing"}, handler.MakeCalls)
+	assert.True(c.ObjectStore.Tls)
+	assert.Equal("eu-west-1", *c.ObjectStore.Region)
+	assert.Len(c.ObjectStore.Buckets, 2)
+	assert.Equal("eu-west-1", *c.ObjectStore.Buckets[1].Region)
+	assert.Equal("access", *c.ObjectStore.Buckets[1].AccessKey)
+}
@@ -0,0 +1,119 @@
+package objectstore
+
+import (
+	"fmt"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+)
+
+// sharedBucket is a bucket requested by another app that an app has been given access to.
+type sharedBucket struct {
+	crd.SharedBucketSpec
+	Name  string
+	Owner *crd.ClowdApp
+}
+
+// getSharedBuckets resolves the app's shared buckets against the apps in the environment.
+func getSharedBuckets(p *providers.Provider, app *crd.ClowdApp) ([]sharedBucket, error) {
+	if len(app.Spec.SharedBuckets) == 0 {
+		return nil, nil
+	}
+
+	appList, err := p.Env.GetAppsInEnv(p.Ctx, p.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	return findSharedBuckets(p.Env, app, appList)
+}
+
+// findSharedBuckets returns the actual name and owning app of each of the app's shared buckets.
+// The owning app must be one of the app's dependencies and must itself request the bucket.
+func findSharedBuckets(
+	env *crd.ClowdEnvironment, app *crd.ClowdApp, appList *crd.ClowdAppList,
+) ([]sharedBucket, error) {
+	buckets := []sharedBucket{}
+	missing := []string{}
+
+	for _, shared := range app.Spec.SharedBuckets {
+		if !isDependency(app, shared.App) {
+			return nil, errors.New(fmt.Sprintf(
+				"app %q owning shared bucket %q was not found in the dependencies", shared.App, shared.Bucket,
+			))
+		}
+
+		owner := findApp(appList, shared.App)
+		if owner == nil {
+			missing = append(missing, fmt.Sprintf("%s/%s", shared.App, shared.Bucket))
+			continue
+		}
+
+		if !requestsBucket(owner, shared.Bucket) {
+			return nil, errors.New(fmt.Sprintf(
+				"shared bucket %q is not requested by app %q", shared.Bucket, shared.App,
+			))
+		}
+
+		name, err := getBucketName(env, shared.Bucket, owner.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		buckets = append(buckets, sharedBucket{SharedBucketSpec: shared, Name: name, Owner: owner})
+	}
+
+	if len(missing) > 0 {
+		return nil, &errors.MissingDependencies{
+			MissingDeps: map[string][]string{"sharedBuckets": missing},
+		}
+	}
+
+	return buckets, nil
+}
+
+func isDependency(app *crd.ClowdApp, name string) bool {
+	for _, dep := range app.Spec.Dependencies {
+		if dep == name {
+			return true
+		}
+	}
+	return false
+}
+
+func findApp(appList *crd.ClowdAppList, name string) *crd.ClowdApp {
+	for i := range appList.Items {
+		if appList.Items[i].Name == name {
+			return &appList.Items[i]
+		}
+	}
+	return nil
+}
+
+func requestsBucket(app *crd.ClowdApp, bucket string) bool {
+	for _, spec := range app.GetObjectStoreBuckets() {
+		if spec.Name == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// newSharedBucketMissingError returns the error raised when the app owning a shared bucket has not
+// created it yet; the app is requeued until it has.
+func newSharedBucketMissingError(bucket sharedBucket) error {
+	newErr := errors.New(fmt.Sprintf(
+		"bucket %q -- shared bucket has not been created by app %q yet", bucket.Name, bucket.App,
+	))
+	newErr.Requeue = true
+	return newErr
+}
+
+// newReadOnlyUnsupportedError returns the error raised when a read-only shared bucket is requested in
+// a mode where apps share credentials.
+func newReadOnlyUnsupportedError(mode string, bucket sharedBucket) error {
+	return errors.New(fmt.Sprintf(
+		"shared bucket %q -- read-only access is not supported in %s mode", bucket.Name, mode,
+	))
+}
@@ -0,0 +1,86 @@
+package objectstore
+
+import (
+	"encoding/json"
+	errlib "errors"
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getSharedBucketApps() (*crd.ClowdApp, *crd.ClowdApp) {
+	owner := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "owner-ns"},
+		Spec:       crd.ClowdAppSpec{ObjectStore: []string{"payloads"}},
+	}
+	consumer := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "testApp", Namespace: "testNamespace"},
+		Spec: crd.ClowdAppSpec{
+			Dependencies:  []string{"ingress"},
+			SharedBuckets: []crd.SharedBucketSpec{{App: "ingress", Bucket: "payloads", ReadOnly: true}},
+		},
+	}
+	return owner, consumer
+}
+
+func TestFindSharedBuckets(t *testing.T) {
+	assert := assert.New(t)
+
+	owner, consumer := getSharedBucketApps()
+	appList := &crd.ClowdAppList{Items: []crd.ClowdApp{*owner, *consumer}}
+
+	buckets, err := findSharedBuckets(getTemplatedEnv("{{.Namespace}}-{{.Bucket}}"), consumer, appList)
+	assert.NoError(err)
+	if assert.Len(buckets, 1) {
+		assert.Equal("owner-ns-payloads", buckets[0].Name)
+		assert.Equal("ingress", buckets[0].Owner.Name)
+	}
+
+	_, err = findSharedBuckets(getTemplatedEnv(""), consumer, &crd.ClowdAppList{})
+	var depErr *errors.MissingDependencies
+	assert.True(errlib.As(err, &depErr))
+
+	consumer.Spec.SharedBuckets[0].Bucket = "other"
+	_, err = findSharedBuckets(getTemplatedEnv(""), consumer, appList)
+	assert.Error(err)
+
+	consumer.Spec.SharedBuckets[0].Bucket = "payloads"
+	consumer.Spec.Dependencies = nil
+	_, err = findSharedBuckets(getTemplatedEnv(""), consumer, appList)
+	assert.Error(err)
+}
+
+func TestMinioSharedBuckets(t *testing.T) {
+	assert := assert.New(t)
+
+	owner, consumer := getSharedBucketApps()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(clientgoscheme.AddToScheme(scheme))
+	assert.NoError(crd.AddToScheme(scheme))
+
+	handler, _, mp := setupBucketTest(t, []mockBucket{{Name: "payloads", Exists: true}})
+	mp.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(owner).Build()
+
+	c := config.AppConfig{}
+	assert.NoError(mp.Provide(consumer, &c))
+	assert.Len(handler.MakeCalls, 0)
+	assert.Equal([]config.ObjectStoreBucket{wantBucket("payloads")}, c.ObjectStore.Buckets)
+
+	policy := bucketPolicy{}
+	assert.NoError(json.Unmarshal(handler.Policies[testAppAccessKey], &policy))
+	assert.Len(policy.Statement, 1)
+	assert.NotContains(policy.Statement[0].Action, "s3:*")
+	assert.Contains(policy.Statement[0].Resource, "arn:aws:s3:::payloads/*")
+
+	handler.MockBuckets = nil
+	mp.Config.Buckets = nil
+	assert.Error(mp.Provide(consumer, &c))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
@@ -76,6 +77,16 @@ type ClowderProvider interface {
 	Provide(app *crd.ClowdApp, c *config.AppConfig) error
 }
 
+// EnvironmentProvider is implemented by providers which have work to perform only when the
+// ClowdEnvironment itself is reconciled, such as cleaning up resources no longer used by any app.
+// Providers are created on every ClowdApp reconcile too, so this work must not be done in their
+// constructors.
+type EnvironmentProvider interface {
+	// EnvProvide performs the environment duty of the provider. A non-zero duration asks for the
+	// environment to be reconciled again after it has elapsed.
+	EnvProvide() (time.Duration, error)
+}
+
 // StrPtr returns a pointer to a string.
 func StrPtr(s string) *string {
 	return &s
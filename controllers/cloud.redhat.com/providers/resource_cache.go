@@ -17,6 +17,7 @@ import (
 	core "k8s.io/api/core/v1"
 
 	cyndi "cloud.redhat.com/clowder/v2/apis/cyndi-operator/v1alpha1"
+	strimziconnect "cloud.redhat.com/clowder/v2/apis/strimzi/v1beta1"
 	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
 	prom "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -95,11 +96,15 @@ func init() {
 	utilruntime.Must(crd.AddToScheme(scheme))
 	utilruntime.Must(strimzi.AddToScheme(scheme))
 	utilruntime.Must(cyndi.AddToScheme(scheme))
+	utilruntime.Must(strimziconnect.AddToScheme(scheme))
 	utilruntime.Must(prom.AddToScheme(scheme))
 
 	gvk, _ := utils.GetKindFromObj(scheme, &strimzi.KafkaTopic{})
 	protectedGVKs[gvk] = true
 
+	gvk, _ = utils.GetKindFromObj(scheme, &strimziconnect.KafkaConnector{})
+	protectedGVKs[gvk] = true
+
 	secretCompare, _ = utils.GetKindFromObj(scheme, &core.Secret{})
 }
 
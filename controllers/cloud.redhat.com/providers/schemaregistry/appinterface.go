@@ -0,0 +1,68 @@
+package schemaregistry
+
+import (
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type appInterfaceSchemaRegistryProvider struct {
+	providers.Provider
+}
+
+// NewAppInterfaceSchemaRegistryProvider creates a new app-interface schema registry provider.
+func NewAppInterfaceSchemaRegistryProvider(p *providers.Provider) (providers.ClowderProvider, error) {
+	return &appInterfaceSchemaRegistryProvider{Provider: *p}, nil
+}
+
+func (sr *appInterfaceSchemaRegistryProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
+	emptyNN := crd.NamespacedName{}
+	credRef := sr.Env.Spec.Providers.SchemaRegistry.CredentialRef
+	if credRef == emptyNN {
+		return errors.New("no schema registry secret defined")
+	}
+
+	sec := &core.Secret{}
+
+	if err := sr.Client.Get(sr.Ctx, types.NamespacedName{
+		Name:      credRef.Name,
+		Namespace: credRef.Namespace,
+	}, sec); err != nil {
+		return err
+	}
+
+	cfg, err := configFromSecret(sec)
+	if err != nil {
+		return err
+	}
+
+	return provideSchemaRegistry(app, c, cfg)
+}
+
+// configFromSecret builds the schema registry config from a secret containing a url and,
+// optionally, a username and password.
+func configFromSecret(sec *core.Secret) (*config.SchemaRegistryConfig, error) {
+	url, ok := sec.Data["url"]
+	if !ok || len(url) == 0 {
+		return nil, errors.New("Missing url in schema registry secret")
+	}
+
+	cfg := &config.SchemaRegistryConfig{
+		Url: string(url),
+	}
+
+	if username, ok := sec.Data["username"]; ok {
+		stringUsername := string(username)
+		cfg.Username = &stringUsername
+	}
+
+	if password, ok := sec.Data["password"]; ok {
+		stringPassword := string(password)
+		cfg.Password = &stringPassword
+	}
+
+	return cfg, nil
+}
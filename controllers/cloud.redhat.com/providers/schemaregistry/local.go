@@ -0,0 +1,148 @@
+package schemaregistry
+
+import (
+	"fmt"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	obj "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/object"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// LocalSchemaRegistryDeployment is the ident refering to the local schema registry deployment object.
+var LocalSchemaRegistryDeployment = providers.NewSingleResourceIdent(ProvName, "schema_registry_deployment", &apps.Deployment{})
+
+// LocalSchemaRegistryService is the ident refering to the local schema registry service object.
+var LocalSchemaRegistryService = providers.NewSingleResourceIdent(ProvName, "schema_registry_service", &core.Service{})
+
+const localSchemaRegistryPort = int32(8080)
+
+type localSchemaRegistryProvider struct {
+	providers.Provider
+	Config config.SchemaRegistryConfig
+}
+
+// NewLocalSchemaRegistryProvider returns a new local schema registry provider object.
+func NewLocalSchemaRegistryProvider(p *providers.Provider) (providers.ClowderProvider, error) {
+	objList := []providers.ResourceIdent{
+		LocalSchemaRegistryDeployment,
+		LocalSchemaRegistryService,
+	}
+
+	if err := providers.CachedMakeComponent(p.Cache, objList, p.Env, "schema-registry", makeLocalSchemaRegistry, false, p.Env.IsNodePort()); err != nil {
+		return nil, err
+	}
+
+	srp := &localSchemaRegistryProvider{
+		Provider: *p,
+		Config: config.SchemaRegistryConfig{
+			// Apicurio serves a Confluent compatible API under this path, which is what most
+			// Kafka serdes libraries expect.
+			Url: fmt.Sprintf(
+				"http://%s-schema-registry.%s.svc:%d/apis/ccompat/v6",
+				p.Env.Name, p.Env.Status.TargetNamespace, localSchemaRegistryPort,
+			),
+		},
+	}
+
+	return srp, nil
+}
+
+func (sr *localSchemaRegistryProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
+	cfg := sr.Config
+
+	// schemas can only be registered once the registry is running
+	if len(app.Spec.KafkaSchemas) > 0 {
+		dd := &apps.Deployment{}
+		if err := sr.Cache.Get(LocalSchemaRegistryDeployment, dd); err != nil {
+			return err
+		}
+
+		if dd.Status.ReadyReplicas == 0 {
+			if c.Kafka != nil {
+				c.Kafka.SchemaRegistry = &cfg
+			}
+			setSchemasCondition(app, core.ConditionFalse, "RegistryNotReady", "Waiting for the schema registry to be ready")
+			newErr := errors.New("schema registry is not ready")
+			newErr.Requeue = true
+			return newErr
+		}
+	}
+
+	return provideSchemaRegistry(app, c, &cfg)
+}
+
+func makeLocalSchemaRegistry(o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool) {
+	nn := providers.GetNamespacedName(o, "schema-registry")
+
+	dd := objMap[LocalSchemaRegistryDeployment].(*apps.Deployment)
+	svc := objMap[LocalSchemaRegistryService].(*core.Service)
+
+	labels := o.GetLabels()
+	labels["env-app"] = nn.Name
+
+	labeler := utils.MakeLabeler(nn, labels, o)
+
+	labeler(dd)
+
+	replicas := int32(1)
+
+	dd.Spec.Replicas = &replicas
+	dd.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+
+	dd.Spec.Template.ObjectMeta.Labels = labels
+
+	ports := []core.ContainerPort{{
+		Name:          "registry",
+		ContainerPort: localSchemaRegistryPort,
+	}}
+
+	livenessProbe := core.Probe{
+		Handler: core.Handler{
+			HTTPGet: &core.HTTPGetAction{
+				Path: "/health/live",
+				Port: intstr.FromInt(int(localSchemaRegistryPort)),
+			},
+		},
+		InitialDelaySeconds: 10,
+		TimeoutSeconds:      2,
+	}
+	readinessProbe := core.Probe{
+		Handler: core.Handler{
+			HTTPGet: &core.HTTPGetAction{
+				Path: "/health/ready",
+				Port: intstr.FromInt(int(localSchemaRegistryPort)),
+			},
+		},
+		InitialDelaySeconds: 10,
+		TimeoutSeconds:      2,
+	}
+
+	// The in-memory registry loses its schemas on restart, they are re-registered on the next
+	// reconciliation of each ClowdApp that declares them.
+	c := core.Container{
+		Name:           nn.Name,
+		Image:          "quay.io/apicurio/apicurio-registry-mem:2.0.1.Final",
+		Ports:          ports,
+		LivenessProbe:  &livenessProbe,
+		ReadinessProbe: &readinessProbe,
+	}
+
+	dd.Spec.Template.Spec.Containers = []core.Container{c}
+	dd.Spec.Template.SetLabels(labels)
+
+	servicePorts := []core.ServicePort{{
+		Name:     "registry",
+		Port:     localSchemaRegistryPort,
+		Protocol: "TCP",
+	}}
+
+	utils.MakeService(svc, nn, labels, servicePorts, o, nodePort)
+}
@@ -0,0 +1,32 @@
+package schemaregistry
+
+import (
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	core "k8s.io/api/core/v1"
+)
+
+type noneSchemaRegistryProvider struct {
+	providers.Provider
+}
+
+// NewNoneSchemaRegistryProvider returns a new none schema registry provider object.
+func NewNoneSchemaRegistryProvider(p *providers.Provider) (providers.ClowderProvider, error) {
+	return &noneSchemaRegistryProvider{Provider: *p}, nil
+}
+
+// Provide reports, as a condition on the app, any schemas it declares which cannot be registered
+// as the environment has no schema registry.
+func (sr *noneSchemaRegistryProvider) Provide(app *crd.ClowdApp, c *config.AppConfig) error {
+	if len(app.Spec.KafkaSchemas) == 0 {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.KafkaSchemasRegistered)
+		return nil
+	}
+
+	setSchemasCondition(
+		app, core.ConditionFalse, "NoSchemaRegistry",
+		"The environment has no schema registry, so the app's schemas were not registered",
+	)
+	return nil
+}
@@ -0,0 +1,33 @@
+package schemaregistry
+
+import (
+	"fmt"
+
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	p "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+)
+
+// ProvName identifies the schemaregistry provider.
+var ProvName = "schemaregistry"
+
+// GetSchemaRegistry returns the correct schema registry provider based on the environment.
+func GetSchemaRegistry(c *p.Provider) (p.ClowderProvider, error) {
+	srMode := c.Env.Spec.Providers.SchemaRegistry.Mode
+	switch srMode {
+	case "local":
+		return NewLocalSchemaRegistryProvider(c)
+	case "app-interface":
+		return NewAppInterfaceSchemaRegistryProvider(c)
+	case "none", "":
+		return NewNoneSchemaRegistryProvider(c)
+	default:
+		errStr := fmt.Sprintf("No matching schemaregistry mode for %s", srMode)
+		return nil, errors.New(errStr)
+	}
+}
+
+func init() {
+	// The schema registry config is nested inside the kafka config, so this must run after
+	// the kafka provider.
+	p.ProvidersRegistration.Register(GetSchemaRegistry, 7, ProvName)
+}
@@ -0,0 +1,112 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	core "k8s.io/api/core/v1"
+)
+
+var registryClient = &http.Client{Timeout: 10 * time.Second}
+
+type registerSchemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+// provideSchemaRegistry adds the registry config to the app's kafka config, if it has one, and
+// registers any schemas the app declares.
+func provideSchemaRegistry(app *crd.ClowdApp, c *config.AppConfig, cfg *config.SchemaRegistryConfig) error {
+	if c.Kafka != nil {
+		c.Kafka.SchemaRegistry = cfg
+	}
+
+	if len(app.Spec.KafkaSchemas) == 0 {
+		crd.RemoveClowdCondition(&app.Status.Conditions, crd.KafkaSchemasRegistered)
+		return nil
+	}
+
+	if err := registerSchemas(cfg, app.Spec.KafkaSchemas); err != nil {
+		setSchemasCondition(app, core.ConditionFalse, "RegistrationFailed", err.Error())
+		return err
+	}
+
+	setSchemasCondition(app, core.ConditionTrue, "SchemasRegistered", "All schemas have been registered")
+	return nil
+}
+
+// setSchemasCondition reports whether the schemas the app declares have been registered as a
+// condition on the app.
+func setSchemasCondition(app *crd.ClowdApp, status core.ConditionStatus, reason string, message string) {
+	condition := crd.ClowdCondition{
+		Type:    crd.KafkaSchemasRegistered,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+	crd.UpdateClowdAppCondition(&app.Status, &condition)
+}
+
+// registerSchemas registers each schema using the Confluent compatible registry API. Registering
+// a schema that is already present returns the existing version, so this is safe to call on
+// every reconciliation. Failures are requeued, as the registry may only be briefly unavailable.
+func registerSchemas(cfg *config.SchemaRegistryConfig, schemas []crd.KafkaSchemaSpec) error {
+	for _, schema := range schemas {
+		if err := registerSchema(cfg, schema); err != nil {
+			newErr := errors.Wrap(fmt.Sprintf("could not register schema for subject %s", schema.Subject), err)
+			newErr.Requeue = true
+			return newErr
+		}
+	}
+	return nil
+}
+
+func registerSchema(cfg *config.SchemaRegistryConfig, schema crd.KafkaSchemaSpec) error {
+	reqBody := registerSchemaRequest{Schema: schema.Schema}
+
+	// AVRO is the registry default and older registries reject an explicit schemaType.
+	if schema.SchemaType != "" && schema.SchemaType != "AVRO" {
+		reqBody.SchemaType = string(schema.SchemaType)
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+
+	reqURL := fmt.Sprintf("%s/subjects/%s/versions", strings.TrimSuffix(cfg.Url, "/"), url.PathEscape(schema.Subject))
+
+	req, err := http.NewRequest("POST", reqURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+
+	if cfg.Username != nil && cfg.Password != nil {
+		req.SetBasicAuth(*cfg.Username, *cfg.Password)
+	}
+
+	resp, err := registryClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		msg := fmt.Sprintf("Bad status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		return errors.New(msg)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	errlib "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/errors"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRegisterSchemas(t *testing.T) {
+	received := map[string]registerSchemaRequest{}
+	auth := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := registerSchemaRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received[r.URL.Path] = body
+		if user, pass, ok := r.BasicAuth(); ok {
+			auth = append(auth, user+":"+pass)
+		}
+		if body.Schema == "broken" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error_code":42201,"message":"Invalid schema"}`))
+			return
+		}
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	username, password := "user", "pass"
+	cfg := &config.SchemaRegistryConfig{
+		Url:      server.URL + "/apis/ccompat/v6/",
+		Username: &username,
+		Password: &password,
+	}
+
+	err := registerSchemas(cfg, []crd.KafkaSchemaSpec{{
+		Subject: "topic-one-value",
+		Schema:  `{"type":"string"}`,
+	}, {
+		Subject:    "topic-two-value",
+		SchemaType: "JSON",
+		Schema:     `{"type":"object"}`,
+	}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, registerSchemaRequest{Schema: `{"type":"string"}`}, received["/apis/ccompat/v6/subjects/topic-one-value/versions"])
+	assert.Equal(t, registerSchemaRequest{Schema: `{"type":"object"}`, SchemaType: "JSON"}, received["/apis/ccompat/v6/subjects/topic-two-value/versions"])
+	assert.Equal(t, []string{"user:pass", "user:pass"}, auth)
+
+	err = registerSchemas(cfg, []crd.KafkaSchemaSpec{{
+		Subject: "topic-three-value",
+		Schema:  "broken",
+	}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "topic-three-value")
+}
+
+func TestProvideSchemaRegistry(t *testing.T) {
+	cfg := &config.SchemaRegistryConfig{Url: "http://registry:8080"}
+	app := &crd.ClowdApp{}
+
+	c := &config.AppConfig{}
+	assert.NoError(t, provideSchemaRegistry(app, c, cfg))
+	assert.Nil(t, c.Kafka, "kafka config should not be created by the schema registry provider")
+
+	c = &config.AppConfig{Kafka: &config.KafkaConfig{}}
+	assert.NoError(t, provideSchemaRegistry(app, c, cfg))
+	assert.Equal(t, cfg, c.Kafka.SchemaRegistry)
+}
+
+func TestLocalSchemaRegistryNotReady(t *testing.T) {
+	cache := providers.NewObjectCache(
+		context.TODO(), fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(), clientgoscheme.Scheme,
+	)
+	nn := types.NamespacedName{Name: "env-schema-registry", Namespace: "env"}
+	assert.NoError(t, cache.Create(LocalSchemaRegistryDeployment, nn, &apps.Deployment{}))
+
+	sr := &localSchemaRegistryProvider{
+		Provider: providers.Provider{Ctx: context.TODO(), Cache: &cache},
+		Config:   config.SchemaRegistryConfig{Url: "http://registry.invalid:8080"},
+	}
+	app := &crd.ClowdApp{Spec: crd.ClowdAppSpec{
+		KafkaSchemas: []crd.KafkaSchemaSpec{{Subject: "topic-one-value", Schema: `{"type":"string"}`}},
+	}}
+
+	err := sr.Provide(app, &config.AppConfig{})
+	var clowderErr *errors.ClowderError
+	assert.True(t, errlib.As(err, &clowderErr))
+	assert.True(t, clowderErr.Requeue)
+	assert.Equal(t, "RegistryNotReady", app.Status.Conditions[0].Reason)
+}
+
+func TestNoneSchemaRegistry(t *testing.T) {
+	sr := &noneSchemaRegistryProvider{}
+	app := &crd.ClowdApp{Spec: crd.ClowdAppSpec{
+		KafkaSchemas: []crd.KafkaSchemaSpec{{Subject: "topic-one-value", Schema: `{"type":"string"}`}},
+	}}
+
+	assert.NoError(t, sr.Provide(app, &config.AppConfig{}))
+	assert.Equal(t, crd.KafkaSchemasRegistered, app.Status.Conditions[0].Type)
+	assert.Equal(t, core.ConditionFalse, app.Status.Conditions[0].Status)
+
+	app.Spec.KafkaSchemas = nil
+	assert.NoError(t, sr.Provide(app, &config.AppConfig{}))
+	assert.Len(t, app.Status.Conditions, 0)
+}
+
+func TestConfigFromSecret(t *testing.T) {
+	_, err := configFromSecret(&core.Secret{Data: map[string][]byte{"username": []byte("user")}})
+	assert.Error(t, err)
+
+	cfg, err := configFromSecret(&core.Secret{Data: map[string][]byte{
+		"url":      []byte("https://registry.example.com"),
+		"username": []byte("user"),
+		"password": []byte("pass"),
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://registry.example.com", cfg.Url)
+	assert.Equal(t, "user", *cfg.Username)
+	assert.Equal(t, "pass", *cfg.Password)
+}
@@ -13,6 +13,7 @@ import (
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/inmemorydb"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/kafka"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/objectstore"
+	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers/schemaregistry"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
 	apps "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -37,6 +38,7 @@ func NewServiceAccountProvider(p *providers.Provider) (providers.ClowderProvider
 		kafka.LocalKafkaDeployment,
 		kafka.LocalZookeeperDeployment,
 		objectstore.MinioDeployment,
+		schemaregistry.LocalSchemaRegistryDeployment,
 	}
 
 	for _, resourceIdent := range resourceIdentsToUpdate {
@@ -1,6 +1,11 @@
 package providers
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
 	"cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1/common"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
 	obj "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/object"
@@ -116,3 +121,75 @@ func MakeLocalDBService(s *core.Service, nn types.NamespacedName, baseResource o
 func MakeLocalDBPVC(pvc *core.PersistentVolumeClaim, nn types.NamespacedName, baseResource obj.ClowdObject) {
 	utils.MakePVC(pvc, nn, providers.Labels{"service": "db", "app": baseResource.GetClowdName()}, "1Gi", baseResource)
 }
+
+// MakeLocalDBConfigMap populates the given configmap object with a postgresql config file holding
+// the requested server parameters and a start script which creates the requested extensions.
+func MakeLocalDBConfigMap(cm *core.ConfigMap, nn types.NamespacedName, baseResource obj.ClowdObject, extensions []string, parameters map[string]string) {
+	labels := providers.Labels{"service": "db", "app": baseResource.GetClowdName()}
+	labler := utils.MakeLabeler(nn, labels, baseResource)
+	labler(cm)
+
+	keys := []string{}
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conf := ""
+	for _, key := range keys {
+		conf += fmt.Sprintf("%s = '%s'\n", key, strings.ReplaceAll(parameters[key], "'", "''"))
+	}
+
+	script := ""
+	if len(extensions) > 0 {
+		script = "psql -v ON_ERROR_STOP=1 -d \"$POSTGRESQL_DATABASE\" <<'EOF'\n"
+		for _, extension := range extensions {
+			script += fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS \"%s\";\n", strings.ReplaceAll(extension, "\"", "\"\""))
+		}
+		script += "EOF\n"
+	}
+
+	cm.Data = map[string]string{
+		"clowder.conf":          conf,
+		"clowder-extensions.sh": script,
+	}
+}
+
+// MountLocalDBConfig mounts the configmap created by MakeLocalDBConfigMap into the local DB
+// container, where the postgresql image picks up config files and start scripts. The pod is
+// annotated with a hash of the configmap data so that changes cause the DB to be restarted.
+func MountLocalDBConfig(dd *apps.Deployment, nn types.NamespacedName, cm *core.ConfigMap) {
+	volName := nn.Name + "-config"
+
+	dd.Spec.Template.Spec.Volumes = append(dd.Spec.Template.Spec.Volumes, core.Volume{
+		Name: volName,
+		VolumeSource: core.VolumeSource{
+			ConfigMap: &core.ConfigMapVolumeSource{
+				LocalObjectReference: core.LocalObjectReference{
+					Name: cm.Name,
+				},
+			},
+		},
+	})
+
+	c := &dd.Spec.Template.Spec.Containers[0]
+	c.VolumeMounts = append(c.VolumeMounts, core.VolumeMount{
+		Name:      volName,
+		MountPath: "/opt/app-root/src/postgresql-cfg/clowder.conf",
+		SubPath:   "clowder.conf",
+	}, core.VolumeMount{
+		Name:      volName,
+		MountPath: "/opt/app-root/src/postgresql-start/clowder-extensions.sh",
+		SubPath:   "clowder-extensions.sh",
+	})
+
+	h := sha256.New()
+	h.Write([]byte(cm.Data["clowder.conf"] + cm.Data["clowder-extensions.sh"]))
+
+	annotations := dd.Spec.Template.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations["configHash"] = fmt.Sprintf("%x", h.Sum(nil))
+	dd.Spec.Template.SetAnnotations(annotations)
+}
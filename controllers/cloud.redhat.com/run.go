@@ -10,6 +10,7 @@ import (
 	cloudredhatcomv1alpha1 "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
 	cyndi "cloud.redhat.com/clowder/v2/apis/cyndi-operator/v1alpha1"
+	strimziconnect "cloud.redhat.com/clowder/v2/apis/strimzi/v1beta1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/utils"
 	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
 	prom "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
@@ -50,6 +51,7 @@ func init() {
 	utilruntime.Must(cloudredhatcomv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(strimzi.AddToScheme(scheme))
 	utilruntime.Must(cyndi.AddToScheme(scheme))
+	utilruntime.Must(strimziconnect.AddToScheme(scheme))
 	utilruntime.Must(prom.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 
@@ -40,6 +40,7 @@ import (
 	ctrlzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	crd "cloud.redhat.com/clowder/v2/apis/cloud.redhat.com/v1alpha1"
+	strimziconnect "cloud.redhat.com/clowder/v2/apis/strimzi/v1beta1"
 	"cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/config"
 	p "cloud.redhat.com/clowder/v2/controllers/cloud.redhat.com/providers"
 	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta1"
@@ -86,6 +87,12 @@ func TestMain(m *testing.M) {
 		logger.Fatal("Failed to add scheme", zap.Error(err))
 	}
 
+	err = strimziconnect.AddToScheme(clientgoscheme.Scheme)
+
+	if err != nil {
+		logger.Fatal("Failed to add scheme", zap.Error(err))
+	}
+
 	// +kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: clientgoscheme.Scheme})